@@ -0,0 +1,52 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// gitToken returns the token used to authenticate HTTPS clones, if any.
+func gitToken() string {
+	return os.Getenv("MONHANG_GIT_TOKEN")
+}
+
+// tokenEnv returns the environment authenticating git with token when it
+// talks to the host of an https repository. The token is passed as an HTTP
+// header through git configuration environment variables, so that it is
+// neither recorded in the clone's remote URL nor visible in the process list.
+// URLs that already carry credentials are left alone.
+func tokenEnv(repo, token string) []string {
+	if token == "" || !strings.HasPrefix(repo, "https://") {
+		return nil
+	}
+	u, err := url.Parse(repo)
+	if err != nil || u.User != nil {
+		return nil
+	}
+
+	// Add to the configuration given in the environment, if any
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return []string{
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s://%s/.extraHeader", n, u.Scheme, u.Host),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", n, auth),
+	}
+}
+
+// scrub hides the git token in s, so that it never reaches the logs.
+func scrub(s string) string {
+	token := gitToken()
+	if token == "" {
+		return s
+	}
+	return strings.Replace(s, token, "xxxxx", -1)
+}
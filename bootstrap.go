@@ -4,6 +4,21 @@
 
 package main
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
 var cmdBoot = &Command{
 	Name:  "boot",
 	Args:  "[configfile]",
@@ -14,6 +29,82 @@ Boot fetches and setups the workspace for the component described in the given c
 }
 
 var bootF = cmdBoot.Flag.String("f", "<defaultconfig>", "configuration file")
+var bootDepsF = cmdBoot.Flag.String("deps", "build,runtime,install", "dependency types to fetch")
+var bootJobsF = cmdBoot.Flag.Int("jobs", runtime.NumCPU(), "number of components fetched in parallel")
+var bootSubmodulesF = cmdBoot.Flag.Bool("recurse-submodules", false, "initialize submodules of fetched components")
+var bootDryRunF = cmdBoot.Flag.Bool("dry-run", false, "print what would be fetched without touching the disk")
+var bootFrozenF = cmdBoot.Flag.Bool("frozen", false, "check out the commits recorded in "+lockFilename)
+var bootFormatF = cmdBoot.Flag.String("format", "table", "summary format: table, plain, csv, json or github")
+var bootJSONF = cmdBoot.Flag.Bool("json", false, "shortcut for -format json")
+var bootExitPolicyF = cmdBoot.Flag.String("exit-policy", "any", "when boot fails: any, all, count or none")
+var bootStripANSIF = cmdBoot.Flag.Bool("strip-ansi", true, "strip ANSI escape sequences from hook output")
+var bootComponentsF stringList
+
+// ansiPattern matches ANSI escape sequences, like color codes.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// stripANSI removes ANSI escape sequences from s if -strip-ansi is set.
+func stripANSI(s string) string {
+	if !*bootStripANSIF {
+		return s
+	}
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+func init() {
+	cmdBoot.Flag.Var(&bootComponentsF, "component", "only fetch the named component (repeatable)")
+}
+
+// fetchResult is the outcome of fetching a component.
+type fetchResult struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Repo      string    `json:"repo"`
+	Ref       string    `json:"ref,omitempty"`
+	Commit    string    `json:"commit,omitempty"`
+	Fetched   bool      `json:"fetched,omitempty"` // the ref was fetched after the clone
+	Status    string    `json:"status"`            // cloned, present or failed
+	Error     string    `json:"error,omitempty"`
+	Hint      string    `json:"hint,omitempty"`
+	Output    string    `json:"hook_output,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	err       error
+}
+
+func (res fetchResult) duration() time.Duration {
+	return res.EndTime.Sub(res.StartTime)
+}
+
+// exitPolicies map the number of failed components and the total number of
+// components to the exit status of boot.
+var exitPolicies = map[string]func(failed, total int) int{
+	// any fails if at least one component failed
+	"any": func(failed, total int) int {
+		if failed > 0 {
+			return 1
+		}
+		return 0
+	},
+	// all only fails if every component failed
+	"all": func(failed, total int) int {
+		if failed > 0 && failed == total {
+			return 1
+		}
+		return 0
+	},
+	// count exits with the number of failed components
+	"count": func(failed, total int) int {
+		if failed > 125 {
+			return 125
+		}
+		return failed
+	},
+	// none never fails
+	"none": func(failed, total int) int {
+		return 0
+	},
+}
 
 func getFilename() string {
 	if *bootF != "<defaultconfig>" {
@@ -22,7 +113,273 @@ func getFilename() string {
 	return "./monhang.json"
 }
 
+// parseDepKinds parses a comma separated list of dependency types.
+func parseDepKinds(list string) (map[string]bool, error) {
+	kinds := make(map[string]bool)
+	for _, kind := range strings.Split(list, ",") {
+		kind = strings.TrimSpace(kind)
+		if !isDepKind(kind) {
+			return nil, fmt.Errorf("unknown dependency type: %s", kind)
+		}
+		kinds[kind] = true
+	}
+	return kinds, nil
+}
+
+// waves groups the dependencies of the given types in the order they must be
+// fetched. Components in a wave don't depend on each other and only depend
+// on components of earlier waves, so each wave can be fetched concurrently.
+// A component declared several times, for instance as a build and a runtime
+// dependency, is only fetched once; it is an error if the declarations don't
+// agree on its repository and version.
+func (proj *Project) waves(kinds map[string]bool) ([][]*ComponentRef, error) {
+	// The level of a node is the length of the longest dependency chain
+	// below it. Walking the sorted nodes backwards visits dependencies first.
+	level := make(map[*interface{}]int)
+	byDir := make(map[string]*ComponentRef)
+	var waves [][]*ComponentRef
+	for i := len(proj.sorted) - 1; i >= 0; i-- {
+		n := proj.sorted[i]
+		l := 0
+		for _, m := range proj.graph.Neighbors(n) {
+			if level[m.Value] >= l {
+				l = level[m.Value] + 1
+			}
+		}
+		level[n.Value] = l
+
+		dep, ok := (*n.Value).(*ComponentRef)
+		if !ok || !kinds[dep.kind] {
+			continue
+		}
+
+		dir := filepath.Clean(dep.Dir())
+		if other, ok := byDir[dir]; ok {
+			if resolveRepo(*dep) == resolveRepo(*other) && dep.Version == other.Version {
+				continue
+			}
+			if dep.Name == other.Name {
+				return nil, fmt.Errorf("%s is declared twice with a different repo or version", dep.Name)
+			}
+			return nil, fmt.Errorf("%s and %s are both cloned into %s with a different repo or version",
+				dep.Name, other.Name, dir)
+		}
+		byDir[dir] = dep
+
+		for len(waves) <= l {
+			waves = append(waves, nil)
+		}
+		waves[l] = append(waves[l], dep)
+	}
+	return waves, nil
+}
+
+// progress reports on stderr how many components were fetched so far. On a
+// terminal a single status line is updated in place, otherwise one line is
+// printed for each finished component so the output stays greppable.
+type progress struct {
+	mu    sync.Mutex
+	total int
+	done  int
+	tty   bool
+}
+
+func newProgress(waves [][]*ComponentRef) *progress {
+	p := &progress{tty: isTerminal(os.Stderr)}
+	for _, wave := range waves {
+		p.total += len(wave)
+	}
+	return p
+}
+
+func (p *progress) start(name string) {
+	if !p.tty {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "\r\033[K[%d/%d] fetching %s...", p.done+1, p.total, name)
+}
+
+func (p *progress) finish(name, action string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if !p.tty {
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s %s\n", p.done, p.total, name, action)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K[%d/%d] %s %s", p.done, p.total, name, action)
+	if p.done == p.total {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// cloneFlags returns the git clone flags selected by the boot flags.
+func cloneFlags() []string {
+	if *bootSubmodulesF {
+		return []string{"--recurse-submodules"}
+	}
+	return nil
+}
+
+// exists reports whether the component is already present in the workspace.
+func exists(dep *ComponentRef) bool {
+	_, err := os.Stat(dep.Dir())
+	return err == nil
+}
+
+// fetcher fetches the components of a workspace.
+type fetcher struct {
+	ctx  context.Context
+	jobs int
+	p    *progress
+	refs map[string]string // refs to check out, by component name
+}
+
+// fetch clones a single component unless it is already present, and checks
+// out its ref.
+func (f *fetcher) fetch(dep *ComponentRef) (res fetchResult) {
+	res = fetchResult{Name: dep.Name, Kind: dep.kind, Repo: resolveRepo(*dep), StartTime: time.Now()}
+	defer func() {
+		res.EndTime = time.Now()
+	}()
+
+	fail := func(err error) fetchResult {
+		res.Status = "failed"
+		res.Error = stripANSI(err.Error())
+		res.Hint = errorHint(err)
+		res.err = err
+		return res
+	}
+
+	ref, ok := f.refs[dep.Name]
+	if exists(dep) {
+		mglog.Info("Skipping ", dep.Name, ": already present")
+		res.Ref = ref
+		commit, err := dep.Commit()
+		if err != nil {
+			return fail(err)
+		}
+		// A component left at another commit must not be locked as is
+		if ref != "" {
+			expected, err := dep.Resolve(ref)
+			if err != nil {
+				return fail(fmt.Errorf("%w: %s is not in the clone, checked out at %s", ErrRefMismatch, ref, shortCommit(commit)))
+			}
+			if expected != commit {
+				return fail(fmt.Errorf("%w: checked out at %s instead of %s (%s)", ErrRefMismatch, shortCommit(commit), ref, shortCommit(expected)))
+			}
+		}
+		res.Status = "present"
+		res.Commit = commit
+		return res
+	}
+
+	if !ok {
+		return fail(fmt.Errorf("not found in %s", lockFilename))
+	}
+	res.Ref = ref
+
+	mglog.Info("Fetching ", dep.kind, " dependency ", dep.Name)
+	err := dep.Fetch(f.ctx, append(cloneFlags(), dep.CloneArgs...)...)
+	if err == nil && ref != "" {
+		err = dep.Checkout(f.ctx, ref)
+		// Shallow or single branch clones may lack the ref, fetch and retry
+		if errors.Is(err, ErrRefNotFound) {
+			mglog.Info("Fetching ", ref, " for ", dep.Name)
+			if err = dep.FetchRef(f.ctx, ref); err == nil {
+				res.Fetched = true
+				err = dep.Checkout(f.ctx, ref)
+			}
+		}
+		// The clone initialized the submodules of the default branch
+		if err == nil && *bootSubmodulesF {
+			err = dep.UpdateSubmodules(f.ctx)
+		}
+	}
+	if err == nil {
+		res.Output, err = dep.PostClone(f.ctx)
+		res.Output = stripANSI(res.Output)
+	}
+	if err != nil {
+		// The directory did not exist before, remove the clone so that a
+		// failed or interrupted component is not taken as present, at the
+		// wrong commit, by the next boot.
+		os.RemoveAll(dep.Dir())
+		return fail(err)
+	}
+
+	res.Status = "cloned"
+	res.Commit, _ = dep.Commit()
+	return res
+}
+
+// fetchWave fetches all components in deps using at most f.jobs goroutines.
+// Components not started yet when the context is canceled are reported as
+// failed.
+func (f *fetcher) fetchWave(deps []*ComponentRef) []fetchResult {
+	var wg sync.WaitGroup
+	results := make([]fetchResult, len(deps))
+	sem := make(chan struct{}, f.jobs)
+	for i, dep := range deps {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, dep *ComponentRef) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			f.p.start(dep.Name)
+			results[i] = f.fetch(dep)
+			f.p.finish(dep.Name, results[i].Status)
+		}(i, dep)
+	}
+	wg.Wait()
+	return results
+}
+
+// dryRun prints what boot would do for each component in waves.
+func dryRun(waves [][]*ComponentRef) {
+	clone, present := 0, 0
+	tw := newTable(os.Stdout)
+	for _, wave := range waves {
+		for _, dep := range wave {
+			name := ellipsize(dep.Name, maxNameWidth())
+			if exists(dep) {
+				fmt.Fprintf(tw, "%s\talready present\n", name)
+				present++
+			} else {
+				fmt.Fprintf(tw, "%s\twould clone %s at %s\n", name, resolveRepo(*dep), dep.Version)
+				clone++
+			}
+		}
+	}
+	tw.Flush()
+	fmt.Printf("would clone %d, already present %d\n", clone, present)
+}
+
 func runBoot(cmd *Command, args []string) {
+	kinds, err := parseDepKinds(*bootDepsF)
+	if err != nil {
+		mglog.Fatal(err)
+	}
+	if *bootJobsF < 1 {
+		*bootJobsF = 1
+	}
+	if *bootJSONF {
+		*bootFormatF = "json"
+	} else if os.Getenv("GITHUB_ACTIONS") == "true" && !flagGiven(&cmd.Flag, "format") {
+		*bootFormatF = "github"
+	}
+	if _, ok := summaryFormats[*bootFormatF]; !ok {
+		mglog.Fatal("Unknown summary format: ", *bootFormatF)
+	}
+	exitPolicy, ok := exitPolicies[*bootExitPolicyF]
+	if !ok {
+		mglog.Fatal("Unknown exit policy: ", *bootExitPolicyF)
+	}
+
 	// Parse the toplevel project file
 	proj, err := parseProjectFile(getFilename())
 	if err != nil {
@@ -33,6 +390,65 @@ func runBoot(cmd *Command, args []string) {
 	// proj.Fetch()
 	proj.processDeps()
 	proj.Sort()
+
+	waves, err := proj.waves(kinds)
+	check(err)
+	if len(bootComponentsF) > 0 {
+		waves, err = selectComponents(waves, bootComponentsF)
+		if err != nil {
+			mglog.Fatal(err)
+		}
+	}
+	if *bootDryRunF {
+		dryRun(waves)
+		return
+	}
+
+	if err := requireGit(); err != nil {
+		mglog.Fatal(err)
+	}
+
+	// Cancel running clones on Ctrl-C so no git process is left behind
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	f := &fetcher{
+		ctx:  ctx,
+		jobs: *bootJobsF,
+		p:    newProgress(waves),
+		refs: make(map[string]string),
+	}
+	if *bootFrozenF {
+		lock, err := readLock(lockFilename)
+		check(err)
+		f.refs = lock.commits()
+	} else {
+		for _, wave := range waves {
+			for _, dep := range wave {
+				f.refs[dep.Name] = dep.Version
+			}
+		}
+	}
+
+	var results []fetchResult
+	start := time.Now()
+	for _, wave := range waves {
+		results = append(results, f.fetchWave(wave)...)
+	}
+
+	failed := printSummary(os.Stdout, *bootFormatF, results, time.Since(start))
+	if code := exitPolicy(failed, len(results)); code != 0 {
+		os.Exit(code)
+	}
+	if !*bootFrozenF {
+		lock, err := readLock(lockFilename)
+		if os.IsNotExist(err) {
+			lock, err = &lockFile{}, nil
+		}
+		check(err)
+		lock.update(results)
+		check(lock.write(lockFilename))
+	}
 }
 
 func init() {
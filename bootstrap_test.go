@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetcherFetch(t *testing.T) {
+	defer stubbed()()
+	var calls []string
+	git = func(ctx context.Context, env, args []string) error {
+		calls = append(calls, strings.Join(args, " "))
+		if args[len(args)-1] == "v9.9.9" {
+			return errors.New("git checkout: error: pathspec 'v9.9.9' did not match any file(s) known to git")
+		}
+		return nil
+	}
+	gitOutput = func(dir string, env []string, args ...string) (string, error) {
+		return "0123abcd", nil
+	}
+
+	f := &fetcher{
+		ctx:  context.Background(),
+		refs: map[string]string{"lib1": "v1.0.0", "lib2": "v9.9.9"},
+	}
+
+	res := f.fetch(&ComponentRef{Name: "lib1", Repo: "lib1.git", kind: "build"})
+	if res.Status != "cloned" || res.Commit != "0123abcd" {
+		t.Error("invalid result:", res)
+	}
+	expected := []string{"clone lib1.git lib1", "-C lib1 checkout -q v1.0.0"}
+	if strings.Join(calls, ";") != strings.Join(expected, ";") {
+		t.Error("invalid git calls:", calls)
+	}
+
+	res = f.fetch(&ComponentRef{Name: "lib2", Repo: "lib2.git", kind: "build"})
+	if res.Status != "failed" || !errors.Is(res.err, ErrCheckoutFailed) || !errors.Is(res.err, ErrRefNotFound) {
+		t.Error("invalid result for an unknown version:", res)
+	}
+
+	res = f.fetch(&ComponentRef{Name: "lib3", Repo: "lib3.git", kind: "build"})
+	if res.Status != "failed" || !strings.Contains(res.Error, lockFilename) {
+		t.Error("invalid result for a component without ref:", res)
+	}
+}
+
+func TestFetcherPostClone(t *testing.T) {
+	defer stubbed()()
+	git = func(ctx context.Context, env, args []string) error { return nil }
+	gitOutput = func(dir string, env []string, args ...string) (string, error) { return "0123abcd", nil }
+	runHook = func(ctx context.Context, dir, command string) (string, error) {
+		if command == "false" {
+			return "setup failed", errors.New("exit status 1")
+		}
+		return "installed in " + dir, nil
+	}
+
+	f := &fetcher{
+		ctx:  context.Background(),
+		refs: map[string]string{"lib1": "v1.0.0", "lib2": "v1.0.0"},
+	}
+
+	res := f.fetch(&ComponentRef{Name: "lib1", Repo: "lib1.git", Hooks: &Hooks{PostClone: "npm install"}})
+	if res.Status != "cloned" || res.Output != "installed in lib1" {
+		t.Error("invalid result:", res)
+	}
+
+	res = f.fetch(&ComponentRef{Name: "lib2", Repo: "lib2.git", Hooks: &Hooks{PostClone: "false"}})
+	if res.Status != "failed" || res.Error != "post_clone hook: exit status 1: setup failed" {
+		t.Error("invalid result for a failing hook:", res)
+	}
+}
+
+func TestExitPolicies(t *testing.T) {
+	tests := []struct {
+		policy        string
+		failed, total int
+		code          int
+	}{
+		{"any", 0, 3, 0},
+		{"any", 1, 3, 1},
+		{"all", 2, 3, 0},
+		{"all", 3, 3, 1},
+		{"all", 0, 0, 0},
+		{"count", 2, 3, 2},
+		{"count", 300, 300, 125},
+		{"none", 3, 3, 0},
+	}
+	for _, test := range tests {
+		if code := exitPolicies[test.policy](test.failed, test.total); code != test.code {
+			t.Errorf("%s with %d/%d failed: expected %d, got %d", test.policy, test.failed, test.total, test.code, code)
+		}
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	colored := "\x1b[1;32madded\x1b[0m 12 packages\x1b[K"
+	if s := stripANSI(colored); s != "added 12 packages" {
+		t.Errorf("invalid stripped output: %q", s)
+	}
+
+	*bootStripANSIF = false
+	defer func() { *bootStripANSIF = true }()
+	if s := stripANSI(colored); s != colored {
+		t.Errorf("output stripped with -strip-ansi=false: %q", s)
+	}
+}
+
+func TestFetcherPresent(t *testing.T) {
+	defer stubbed()()
+	dir, err := ioutil.TempDir("", "monhang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	git = func(ctx context.Context, env, args []string) error {
+		t.Error("present component fetched:", args)
+		return nil
+	}
+	gitOutput = func(dir string, env []string, args ...string) (string, error) {
+		switch args[len(args)-1] {
+		case "HEAD":
+			return "0123456789abcdef", nil
+		case "v1.0.0^{commit}":
+			return "0123456789abcdef", nil
+		case "v2.0.0^{commit}":
+			return "fedcba9876543210", nil
+		}
+		return "", errors.New("git rev-parse: ")
+	}
+
+	f := &fetcher{
+		ctx:  context.Background(),
+		refs: map[string]string{"lib1": "v1.0.0", "lib2": "v2.0.0", "lib3": "v3.0.0"},
+	}
+	res := f.fetch(&ComponentRef{Name: "lib1", Path: dir})
+	if res.Status != "present" || res.Commit != "0123456789abcdef" {
+		t.Error("invalid result:", res)
+	}
+	for _, name := range []string{"lib2", "lib3"} {
+		res = f.fetch(&ComponentRef{Name: name, Path: dir})
+		if res.Status != "failed" || res.Commit != "" || !errors.Is(res.err, ErrRefMismatch) {
+			t.Error("invalid result for a component at another version:", res)
+		}
+	}
+	if !strings.Contains(res.Error, "v3.0.0 is not in the clone") {
+		t.Error("invalid error:", res.Error)
+	}
+}
+
+func TestFetcherSubmodules(t *testing.T) {
+	defer stubbed()()
+	var calls []string
+	git = func(ctx context.Context, env, args []string) error {
+		calls = append(calls, strings.Join(args, " "))
+		return nil
+	}
+	gitOutput = func(dir string, env []string, args ...string) (string, error) {
+		return "0123abcd", nil
+	}
+	*bootSubmodulesF = true
+	defer func() { *bootSubmodulesF = false }()
+
+	f := &fetcher{
+		ctx:  context.Background(),
+		refs: map[string]string{"lib1": "v1.0.0"},
+	}
+	if res := f.fetch(&ComponentRef{Name: "lib1", Repo: "lib1.git"}); res.Status != "cloned" {
+		t.Error("invalid result:", res)
+	}
+	expected := []string{
+		"clone --recurse-submodules lib1.git lib1",
+		"-C lib1 checkout -q v1.0.0",
+		"-C lib1 submodule update --init --recursive",
+	}
+	if strings.Join(calls, ";") != strings.Join(expected, ";") {
+		t.Error("invalid git calls:", calls)
+	}
+}
+
+func TestFetcherFetchRef(t *testing.T) {
+	defer stubbed()()
+	var calls []string
+	fetched := false
+	git = func(ctx context.Context, env, args []string) error {
+		calls = append(calls, strings.Join(args, " "))
+		switch {
+		case args[2] == "fetch":
+			fetched = true
+		case args[len(args)-1] == "feature" && !fetched:
+			return errors.New("git checkout: error: pathspec 'feature' did not match any file(s) known to git")
+		}
+		return nil
+	}
+	gitOutput = func(dir string, env []string, args ...string) (string, error) {
+		return "0123abcd", nil
+	}
+
+	f := &fetcher{
+		ctx:  context.Background(),
+		refs: map[string]string{"lib1": "feature"},
+	}
+	res := f.fetch(&ComponentRef{Name: "lib1", Repo: "lib1.git", CloneArgs: []string{"--depth=1"}})
+	if res.Status != "cloned" || !res.Fetched {
+		t.Error("invalid result:", res)
+	}
+	expected := []string{
+		"clone --depth=1 lib1.git lib1",
+		"-C lib1 checkout -q feature",
+		"-C lib1 fetch -q origin +refs/heads/feature:refs/remotes/origin/feature +refs/heads/feature:refs/heads/feature",
+		"-C lib1 checkout -q feature",
+	}
+	if strings.Join(calls, ";") != strings.Join(expected, ";") {
+		t.Error("invalid git calls:", calls)
+	}
+}
+
+func TestFetcherFetchCleanup(t *testing.T) {
+	defer stubbed()()
+	dir, err := ioutil.TempDir("", "monhang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	git = func(ctx context.Context, env, args []string) error {
+		if args[0] == "clone" {
+			return os.MkdirAll(args[len(args)-1], 0755)
+		}
+		return errors.New("git checkout: error: pathspec 'v9.9.9' did not match any file(s) known to git")
+	}
+
+	f := &fetcher{
+		ctx:  context.Background(),
+		refs: map[string]string{"lib1": "v9.9.9"},
+	}
+	dep := &ComponentRef{Name: "lib1", Repo: "lib1.git", Path: filepath.Join(dir, "lib1")}
+	if res := f.fetch(dep); res.Status != "failed" {
+		t.Error("invalid result for an unknown version:", res)
+	}
+	if exists(dep) {
+		t.Error("clone left behind after a failed checkout")
+	}
+}
+
+func TestFetcherPostCloneCleanup(t *testing.T) {
+	defer stubbed()()
+	dir, err := ioutil.TempDir("", "monhang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	git = func(ctx context.Context, env, args []string) error {
+		if args[0] == "clone" {
+			return os.MkdirAll(args[len(args)-1], 0755)
+		}
+		return nil
+	}
+	runHook = func(ctx context.Context, dir, command string) (string, error) {
+		return "", errors.New("exit status 1")
+	}
+
+	f := &fetcher{
+		ctx:  context.Background(),
+		refs: map[string]string{"lib1": "v1.0.0"},
+	}
+	dep := &ComponentRef{Name: "lib1", Repo: "lib1.git", Path: filepath.Join(dir, "lib1"),
+		Hooks: &Hooks{PostClone: "npm install"}}
+	if res := f.fetch(dep); res.Status != "failed" {
+		t.Error("invalid result for a failing hook:", res)
+	}
+	if exists(dep) {
+		t.Error("clone left behind after a failed hook, the next boot would not run it")
+	}
+}
+
+func TestWavesDuplicates(t *testing.T) {
+	proj := &Project{}
+	proj.Name = "top"
+	proj.Deps.Build = []ComponentRef{{Name: "lib1", Repo: "lib1.git", Version: "v1.0.0"}, {Name: "lib2", Repo: "lib2.git"}}
+	proj.Deps.Runtime = []ComponentRef{{Name: "lib1", Repo: "lib1.git", Version: "v1.0.0"}}
+	proj.processDeps()
+	proj.Sort()
+
+	waves, err := proj.waves(allDepKinds())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(waves) != 1 || len(waves[0]) != 2 {
+		t.Error("component declared twice not fetched once:", waves)
+	}
+
+	proj.Deps.Runtime[0].Version = "v2.0.0"
+	proj.processDeps()
+	proj.Sort()
+	_, err = proj.waves(allDepKinds())
+	if err == nil || !strings.Contains(err.Error(), "different repo or version") {
+		t.Error("conflicting declarations not reported:", err)
+	}
+}
@@ -5,20 +5,36 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
 	"github.com/twmb/algoimpl/go/graph"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 )
 
 // ComponentRef is the configuration block that references a component.
 type ComponentRef struct {
 	Name       string      `json:"name"`
+	Alias      string      `json:"alias"`
 	Version    string      `json:"version"`
 	Repo       string      `json:"repo"`
+	Path       string      `json:"path"`
 	Repoconfig *RepoConfig `json:"repoconfig"`
+	CloneArgs  []string    `json:"clone_args"`
+	Hooks      *Hooks      `json:"hooks"`
 	node       graph.Node
+	kind       string
+	// configDir is the directory of the configuration file declaring the
+	// component, used to resolve relative local repositories.
+	configDir string
 }
 
 // Dependency is the configuration block that defines a dependency.
@@ -29,31 +45,137 @@ type Dependency struct {
 	Intall  []ComponentRef `json:"install"`
 }
 
+// depKinds are the dependency types, in the order they are processed.
+var depKinds = []string{"build", "runtime", "install"}
+
+// allDepKinds returns a set with every dependency type.
+func allDepKinds() map[string]bool {
+	kinds := make(map[string]bool)
+	for _, k := range depKinds {
+		kinds[k] = true
+	}
+	return kinds
+}
+
+func isDepKind(kind string) bool {
+	for _, k := range depKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// merge appends the dependencies in other to deps.
+func (deps *Dependency) merge(other Dependency) {
+	deps.Build = append(deps.Build, other.Build...)
+	deps.Runtime = append(deps.Runtime, other.Runtime...)
+	deps.Intall = append(deps.Intall, other.Intall...)
+}
+
+// Hooks are shell commands run in the component directory at given points of
+// the component life cycle.
+type Hooks struct {
+	PostClone string `json:"post_clone"`
+}
+
 // RepoConfig defines the configuration for a repository
 type RepoConfig struct {
 	Type string `json:"type"`
 	Base string `json:"base"`
 }
 
+// BuildConfig defines how a component is built
+type BuildConfig struct {
+	Type string `json:"type"`
+}
+
 // Project is the toplevel struct that represents a configuration file
 type Project struct {
 	ComponentRef
-	Deps   Dependency
-	graph  *graph.Graph
-	sorted []graph.Node
+	SchemaVersion int          `json:"schema_version"`
+	Build         *BuildConfig `json:"build"`
+	Deps          Dependency
+	Includes      []string `json:"includes"`
+	graph         *graph.Graph
+	sorted        []graph.Node
 }
 
-var git = func(args []string) {
-	mglog.Noticef("Executing: git %s\n", args)
-	_, err := exec.Command("git", args...).Output()
-	if err != nil {
-		if ee, ok := err.(*exec.ExitError); ok {
-			msg := string(ee.Stderr[:])
-			mglog.Fatal("Error executing: ", msg)
+// checkDir returns a clear error if dir, where a command is about to run, is
+// missing, for instance because it was removed by another process.
+func checkDir(dir string) error {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s: directory not found", dir)
+	}
+	return nil
+}
+
+// git runs git with args. env is added to the environment of git.
+var git = func(ctx context.Context, env, args []string) error {
+	mglog.Noticef("Executing: git %s\n", scrub(fmt.Sprint(args)))
+	op := args[0]
+	if op == "-C" && len(args) > 2 {
+		if err := checkDir(args[1]); err != nil {
+			return err
 		}
+		op = args[2]
+	}
+	cmd := exec.CommandContext(ctx, *gitBinF, args...)
+	cmd.Env = append(os.Environ(), env...)
+	_, err := cmd.Output()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if ee, ok := err.(*exec.ExitError); ok {
+		msg := scrub(strings.TrimSpace(string(ee.Stderr[:])))
+		mglog.Error("Error executing: ", msg)
+		return fmt.Errorf("git %s: %s", op, msg)
+	}
+	return err
+}
 
-		mglog.Fatal(err)
+// gitOutput runs git in dir and returns its trimmed standard output. env is
+// added to the environment of git.
+var gitOutput = func(dir string, env []string, args ...string) (string, error) {
+	mglog.Debugf("Executing in %s: git %s\n", dir, scrub(fmt.Sprint(args)))
+	if err := checkDir(dir); err != nil {
+		return "", err
 	}
+	cmd := exec.Command(*gitBinF, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.Output()
+	if ee, ok := err.(*exec.ExitError); ok {
+		msg := scrub(strings.TrimSpace(string(ee.Stderr[:])))
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(string(out)), err
+}
+
+var gitOnce sync.Once
+var gitErr error
+
+// requireGit checks that git, or the executable given by -git-bin, can be
+// run. The check is done once per process.
+func requireGit() error {
+	gitOnce.Do(func() {
+		if _, err := exec.LookPath(*gitBinF); err != nil && *gitBinF == "git" {
+			gitErr = errors.New("git is required but not found on PATH")
+		} else if err != nil {
+			gitErr = fmt.Errorf("git executable %s not found", *gitBinF)
+		} else if _, err := gitOutput(".", nil, "--version"); err != nil {
+			gitErr = fmt.Errorf("git is installed but does not run: %v", err)
+		}
+	})
+	return gitErr
+}
+
+// isGitRepository reports whether dir is the root of a git repository. Git
+// worktrees and submodules have a .git file pointing to the repository
+// instead of a .git directory.
+func isGitRepository(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil && (info.IsDir() || info.Mode().IsRegular())
 }
 
 func resolveRepo(comp ComponentRef) string {
@@ -63,54 +185,320 @@ func resolveRepo(comp ComponentRef) string {
 	} else {
 		repo = comp.Repo
 	}
+	return localRepo(comp.configDir, repo)
+}
+
+// localRepo resolves a relative local repository against dir. Relative local
+// repositories are paths starting with ./ or ../ and file: URLs with a
+// relative path, like file:../repos/lib1.git. Other repositories are
+// returned unchanged, as are all repositories if dir is empty.
+func localRepo(dir, repo string) string {
+	if dir == "" {
+		return repo
+	}
+	if strings.HasPrefix(repo, "file:") {
+		p := strings.TrimPrefix(strings.TrimPrefix(repo, "file:"), "//")
+		if filepath.IsAbs(p) {
+			return repo
+		}
+		return "file://" + filepath.Join(dir, p)
+	}
+	if strings.HasPrefix(repo, "./") || strings.HasPrefix(repo, "../") {
+		return filepath.Join(dir, repo)
+	}
 	return repo
 }
 
-// Fetch the specified component
-func (comp ComponentRef) Fetch() {
+// Dir returns the directory of the component in the workspace: its path if
+// given, otherwise its name.
+func (comp ComponentRef) Dir() string {
+	if comp.Path != "" {
+		return filepath.FromSlash(comp.Path)
+	}
+	return comp.Name
+}
+
+// Fetch the specified component. Flags are passed to git clone. HTTPS
+// repositories are authenticated with the MONHANG_GIT_TOKEN token, if set.
+func (comp ComponentRef) Fetch(ctx context.Context, flags ...string) error {
 	repo := resolveRepo(comp)
-	args := []string{"clone", repo, comp.Name}
-	git(args)
+	args := append([]string{"clone"}, flags...)
+	args = append(args, repo, comp.Dir())
+	return newGitError("clone", git(ctx, tokenEnv(repo, gitToken()), args))
+}
+
+// Checkout checks out ref in the component working tree.
+func (comp ComponentRef) Checkout(ctx context.Context, ref string) error {
+	return newGitError("checkout", git(ctx, nil, []string{"-C", comp.Dir(), "checkout", "-q", ref}))
+}
+
+// UpdateSubmodules checks out the submodules recorded in the commit checked
+// out in the component working tree, recursively.
+func (comp ComponentRef) UpdateSubmodules(ctx context.Context) error {
+	return newGitError("submodule", git(ctx, tokenEnv(resolveRepo(comp), gitToken()),
+		[]string{"-C", comp.Dir(), "submodule", "update", "--init", "--recursive"}))
+}
+
+// runHook runs a hook command with sh in dir and returns its combined output.
+var runHook = func(ctx context.Context, dir, command string) (string, error) {
+	mglog.Noticef("Executing in %s: %s\n", dir, command)
+	if err := checkDir(dir); err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// PostClone runs the post_clone hook of the component, if any.
+func (comp ComponentRef) PostClone(ctx context.Context) (string, error) {
+	if comp.Hooks == nil || comp.Hooks.PostClone == "" {
+		return "", nil
+	}
+	out, err := runHook(ctx, comp.Dir(), comp.Hooks.PostClone)
+	if err != nil && out != "" {
+		return out, fmt.Errorf("post_clone hook: %v: %s", err, out)
+	} else if err != nil {
+		return out, fmt.Errorf("post_clone hook: %v", err)
+	}
+	return out, nil
+}
+
+// FetchRef fetches ref from the origin remote into the component working
+// tree, so that it can be checked out. ref is tried as a branch, then as a
+// tag and last as a commit. A branch is also fetched into a local branch, as
+// single branch clones do not check out other remote branches by name.
+func (comp ComponentRef) FetchRef(ctx context.Context, ref string) error {
+	env := tokenEnv(resolveRepo(comp), gitToken())
+	var err error
+	for _, refspecs := range [][]string{
+		{"+refs/heads/" + ref + ":refs/remotes/origin/" + ref, "+refs/heads/" + ref + ":refs/heads/" + ref},
+		{"+refs/tags/" + ref + ":refs/tags/" + ref},
+		{ref},
+	} {
+		args := append([]string{"-C", comp.Dir(), "fetch", "-q", "origin"}, refspecs...)
+		err = git(ctx, env, args)
+		if err == nil {
+			return nil
+		}
+	}
+	return newGitError("fetch", err)
+}
+
+// Resolve returns the commit ref points to in the component working tree. A
+// branch that only exists in the origin remote is resolved too.
+func (comp ComponentRef) Resolve(ref string) (string, error) {
+	commit, err := gitOutput(comp.Dir(), nil, "rev-parse", "--verify", "-q", ref+"^{commit}")
+	if err != nil {
+		commit, err = gitOutput(comp.Dir(), nil, "rev-parse", "--verify", "-q", "origin/"+ref+"^{commit}")
+	}
+	return commit, err
+}
+
+// Commit returns the commit checked out in the component working tree.
+func (comp ComponentRef) Commit() (string, error) {
+	return gitOutput(comp.Dir(), nil, "rev-parse", "HEAD")
 }
 
 // Project methods
 
+// readConfig reads a configuration file. The filename "-" reads it from
+// the standard input and http(s) URLs are downloaded. Files with a .toml
+// extension are converted to JSON, so both formats are validated and parsed
+// the same way.
+func readConfig(filename string) ([]byte, error) {
+	var data []byte
+	var err error
+	switch {
+	case filename == "-":
+		data, err = ioutil.ReadAll(os.Stdin)
+	case isURL(filename):
+		data, err = fetchConfig(filename)
+	default:
+		data, err = ioutil.ReadFile(filename)
+	}
+	if err != nil || !strings.HasSuffix(filename, ".toml") {
+		return data, err
+	}
+	return tomlToJSON(data)
+}
+
+// tomlToJSON converts a TOML document to JSON.
+func tomlToJSON(data []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// resolveInclude resolves an include relative to the file including it.
+func resolveInclude(parent, inc string) string {
+	switch {
+	case isURL(inc) || filepath.IsAbs(inc) || parent == "-":
+		return inc
+	case isURL(parent):
+		base, err := url.Parse(parent)
+		if err != nil {
+			return inc
+		}
+		ref, err := url.Parse(inc)
+		if err != nil {
+			return inc
+		}
+		return base.ResolveReference(ref).String()
+	}
+	return filepath.Join(filepath.Dir(parent), inc)
+}
+
 func parseProjectFile(filename string) (*Project, error) {
+	return parseProjectIncludes(filename, nil)
+}
+
+// parseProjectIncludes parses a configuration file and merges into it the
+// dependencies of the files it includes. Relative includes are resolved
+// against the directory of the including file. The stack holds the files
+// currently being parsed and is used to detect include cycles.
+func parseProjectIncludes(filename string, stack []string) (*Project, error) {
+	path := filename
+	if filename != "-" && !isURL(filename) {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return nil, err
+		}
+		path = abs
+	}
+	for _, f := range stack {
+		if f == path {
+			cycle := strings.Join(append(stack, path), " -> ")
+			return nil, fmt.Errorf("include cycle: %s", cycle)
+		}
+	}
+
 	var data []byte
-	data, err := ioutil.ReadFile(filename)
-	if ee, ok := err.(*os.PathError); ok {
-		mglog.Error("Error: ", ee)
+	data, err := readConfig(filename)
+	if err != nil {
+		mglog.Error("Error: ", err)
 		return nil, err
 	}
 
+	if *noValidateF {
+		mglog.Warning("NOT validating ", filename, ": unknown fields are ignored")
+	} else if err = validateProjectJSON(data, len(stack) > 0); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
 	var proj Project
-	err = json.Unmarshal(data, &proj)
+	if err = json.Unmarshal(data, &proj); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if filename != "-" && !isURL(filename) {
+		proj.setConfigDir(filepath.Dir(path))
+	}
+
+	for _, inc := range proj.Includes {
+		inc = resolveInclude(path, inc)
+		mglog.Debug("Including ", inc)
+		sub, err := parseProjectIncludes(inc, append(stack, path))
+		if err != nil {
+			return nil, err
+		}
+		// The repoconfig of an included file applies to its own
+		// dependencies, not the including file's one.
+		sub.inheritRepoConfig()
+		proj.Deps.merge(sub.Deps)
+	}
+
+	// Components and aliases must be unique across all the included files
+	if len(stack) == 0 && !*noValidateF {
+		if err = checkDuplicates(proj.Deps, proj.Repoconfig); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		if err = checkAliases(proj.Deps); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+	}
 	return &proj, nil
 }
 
+// setConfigDir records dir as the configuration directory of the project
+// and of its dependencies. Relative local repository bases are made absolute
+// against dir, as dependencies declared in other files may inherit them.
+func (proj *Project) setConfigDir(dir string) {
+	proj.configDir = dir
+	proj.Repoconfig.resolveBase(dir)
+	for _, deps := range [][]ComponentRef{proj.Deps.Build, proj.Deps.Runtime, proj.Deps.Intall} {
+		for i := range deps {
+			deps[i].configDir = dir
+			deps[i].Repoconfig.resolveBase(dir)
+		}
+	}
+}
+
+// resolveBase resolves a relative local base against dir, keeping its
+// trailing slash.
+func (rc *RepoConfig) resolveBase(dir string) {
+	if rc == nil || rc.Base == "" {
+		return
+	}
+	base := localRepo(dir, rc.Base)
+	if strings.HasSuffix(rc.Base, "/") && !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	rc.Base = base
+}
+
+// inheritRepoConfig sets the repository configuration of the project on its
+// dependencies that have none.
+func (proj *Project) inheritRepoConfig() {
+	if proj.Repoconfig == nil {
+		return
+	}
+	for _, deps := range [][]ComponentRef{proj.Deps.Build, proj.Deps.Runtime, proj.Deps.Intall} {
+		for i := range deps {
+			if deps[i].Repoconfig == nil {
+				deps[i].Repoconfig = proj.Repoconfig
+			}
+		}
+	}
+}
+
 func (proj *Project) processDeps() {
 	proj.graph = graph.New(graph.Directed)
 	proj.node = proj.graph.MakeNode()
 	*proj.node.Value = proj
 
 	// Build the dependency graph
-	for _, dep := range proj.Deps.Build {
-		mglog.Debug("Processing build dependency ", dep.Name)
+	for i := range proj.Deps.Build {
+		proj.addDep(&proj.Deps.Build[i], "build")
+	}
+	for i := range proj.Deps.Runtime {
+		proj.addDep(&proj.Deps.Runtime[i], "runtime")
+	}
+	for i := range proj.Deps.Intall {
+		proj.addDep(&proj.Deps.Intall[i], "install")
+	}
+}
 
-		// Create dependency edge
-		dep.node = proj.graph.MakeNode()
-		*dep.node.Value = dep
-		proj.graph.MakeEdge(proj.node, dep.node)
+// addDep adds dep to the dependency graph with an edge from the project.
+func (proj *Project) addDep(dep *ComponentRef, kind string) {
+	mglog.Debug("Processing ", kind, " dependency ", dep.Name)
 
-		if dep.Repoconfig == nil {
-			mglog.Debug("Adding toplevel repoconfig to dep:", *proj.Repoconfig)
-			dep.Repoconfig = proj.Repoconfig
-		}
+	if dep.Repoconfig == nil {
+		mglog.Debug("Adding toplevel repoconfig to dep:", proj.Repoconfig)
+		dep.Repoconfig = proj.Repoconfig
 	}
+
+	// Create dependency edge
+	dep.kind = kind
+	dep.node = proj.graph.MakeNode()
+	*dep.node.Value = dep
+	proj.graph.MakeEdge(proj.node, dep.node)
 }
 
 // Sort iterates all build dependencies
-func (proj Project) Sort() {
+func (proj *Project) Sort() {
 	mglog.Debug("Sorting project ", proj.Name)
 	proj.sorted = proj.graph.TopologicalSort()
 }
@@ -0,0 +1,122 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+var cmdComponents = &Command{
+	Name:  "components",
+	Args:  "[-f configfile] [-tree]",
+	Short: "list the declared components",
+	Long: `
+Components lists the components declared in the given configuration file with
+their alias, version, repository type and whether they are present in the
+workspace.
+Nothing is fetched. Use -tree to show them grouped by dependency type.
+`,
+}
+
+var componentsF = cmdComponents.Flag.String("f", "./monhang.json", "configuration file")
+var componentsTreeF = cmdComponents.Flag.Bool("tree", false, "print the components as a tree")
+
+// repoType returns the repository type of a component, git by default.
+func repoType(dep *ComponentRef) string {
+	if dep.Repoconfig != nil && dep.Repoconfig.Type != "" {
+		return dep.Repoconfig.Type
+	}
+	return "git"
+}
+
+// presence describes whether a component is present in the workspace.
+func presence(dep *ComponentRef) string {
+	if exists(dep) {
+		return "present"
+	}
+	return "missing"
+}
+
+// depsByKind returns the dependencies of the project for each dependency type.
+func (proj *Project) depsByKind() map[string][]ComponentRef {
+	return map[string][]ComponentRef{
+		"build":   proj.Deps.Build,
+		"runtime": proj.Deps.Runtime,
+		"install": proj.Deps.Intall,
+	}
+}
+
+func writeComponentTable(w io.Writer, proj *Project) {
+	tw := newTable(w)
+	fmt.Fprintln(tw, "COMPONENT\tALIAS\tVERSION\tKIND\tTYPE\tSTATUS")
+	deps := proj.depsByKind()
+	for _, kind := range depKinds {
+		for i := range deps[kind] {
+			dep := &deps[kind][i]
+			alias := dep.Alias
+			if alias == "" {
+				alias = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				ellipsize(dep.Name, maxNameWidth()), alias, dep.Version, kind, repoType(dep), presence(dep))
+		}
+	}
+	tw.Flush()
+}
+
+func writeComponentTree(w io.Writer, proj *Project) {
+	if proj.Version != "" {
+		fmt.Fprintln(w, proj.Name, proj.Version)
+	} else {
+		fmt.Fprintln(w, proj.Name)
+	}
+
+	deps := proj.depsByKind()
+	var kinds []string
+	for _, kind := range depKinds {
+		if len(deps[kind]) > 0 {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	for k, kind := range kinds {
+		branch, indent := "├── ", "│   "
+		if k == len(kinds)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintln(w, branch+kind)
+		for i := range deps[kind] {
+			dep := &deps[kind][i]
+			leaf := "├── "
+			if i == len(deps[kind])-1 {
+				leaf = "└── "
+			}
+			name := dep.Name
+			if dep.Alias != "" {
+				name += " [" + dep.Alias + "]"
+			}
+			fmt.Fprintf(w, "%s%s%s %s (%s, %s)\n", indent, leaf, name, dep.Version, repoType(dep), presence(dep))
+		}
+	}
+}
+
+func runComponents(cmd *Command, args []string) {
+	proj, err := parseProjectFile(*componentsF)
+	check(err)
+	// Dependencies inherit the repository configuration of the project
+	proj.processDeps()
+
+	if *componentsTreeF {
+		writeComponentTree(os.Stdout, proj)
+	} else {
+		writeComponentTable(os.Stdout, proj)
+	}
+}
+
+func init() {
+	cmdComponents.Run = runComponents // break init loop
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteComponentTree(t *testing.T) {
+	proj := &Project{}
+	proj.Name = "top"
+	proj.Version = "1.0.0"
+	proj.Repoconfig = &RepoConfig{Type: "git", Base: "git@github.com:monhang/"}
+	proj.Deps.Build = []ComponentRef{{Name: "lib1", Version: "v1.0.0"}, {Name: "lib2", Alias: "l2", Version: "v2.0.0"}}
+	proj.Deps.Intall = []ComponentRef{{Name: "tool1", Version: "v0.1.0"}}
+	proj.processDeps()
+
+	var buf bytes.Buffer
+	writeComponentTree(&buf, proj)
+
+	expected := `top 1.0.0
+├── build
+│   ├── lib1 v1.0.0 (git, missing)
+│   └── lib2 [l2] v2.0.0 (git, missing)
+└── install
+    └── tool1 v0.1.0 (git, missing)
+`
+	if buf.String() != expected {
+		t.Errorf("invalid tree:\n%s", buf.String())
+	}
+}
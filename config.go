@@ -0,0 +1,93 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"github.com/BurntSushi/toml"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// settings holds the defaults read from the monhang configuration files.
+type settings struct {
+	Config    string `toml:"config"`
+	Jobs      int    `toml:"jobs"`
+	LogLevel  string `toml:"log_level"`
+	LogFormat string `toml:"log_format"`
+}
+
+// flagEnv maps flags to the environment variables that also set them.
+// Environment variables take precedence over the configuration files.
+var flagEnv = map[string]string{
+	"log-format": "MONHANG_LOG_FORMAT",
+	"log-level":  "MONHANG_LOG_LEVEL",
+}
+
+// settingsFiles returns the configuration files in the order they are read:
+// the user configuration first, then the one in the current directory.
+func settingsFiles() []string {
+	var files []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		files = append(files, filepath.Join(dir, "monhang", "config.toml"))
+	}
+	return append(files, ".monhang.toml")
+}
+
+// loadSettings reads the configuration files. Values in later files override
+// values in earlier ones. Missing files are not an error.
+func loadSettings() (settings, error) {
+	var s settings
+	for _, file := range settingsFiles() {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if _, err := toml.DecodeFile(file, &s); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// values returns the settings as flag values, keyed by flag name.
+func (s settings) values() map[string]string {
+	values := make(map[string]string)
+	if s.Config != "" {
+		values["f"] = s.Config
+	}
+	if s.Jobs > 0 {
+		values["jobs"] = strconv.Itoa(s.Jobs)
+	}
+	if s.LogLevel != "" {
+		values["log-level"] = s.LogLevel
+	}
+	if s.LogFormat != "" {
+		values["log-format"] = s.LogFormat
+	}
+	return values
+}
+
+// apply sets the flags of fs that were not given on the command line, nor
+// through the environment, to the values in the settings.
+func (s settings) apply(fs *flag.FlagSet) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	for name, value := range s.values() {
+		if given[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if env, ok := flagEnv[name]; ok && os.Getenv(env) != "" {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
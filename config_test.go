@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestSettingsApply(t *testing.T) {
+	var fs flag.FlagSet
+	config := fs.String("f", "./monhang.json", "")
+	jobs := fs.Int("jobs", 1, "")
+	fs.Parse([]string{"-jobs", "2"})
+
+	s := settings{Config: "ws.json", Jobs: 8}
+	if err := s.apply(&fs); err != nil {
+		t.Fatal(err)
+	}
+	if *config != "ws.json" {
+		t.Error("setting not applied:", *config)
+	}
+	if *jobs != 2 {
+		t.Error("setting overrode command line flag:", *jobs)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+var cmdDoctor = &Command{
+	Name:  "doctor",
+	Args:  "[-f configfile] [-strict]",
+	Short: "diagnose the environment and the workspace",
+	Long: `
+Doctor checks that git is installed, that the configuration file is valid and
+that every declared component is present in the workspace, is a git
+repository, has a clean working tree and is on a branch, or at its declared
+version or the commit recorded in ` + lockFilename + ` as after boot. Stashed
+changes are reported as well, as they are easily forgotten.
+
+It exits with a non-zero status if git is missing or the configuration file
+is invalid. With -strict, warnings like local changes or a detached HEAD also
+make it exit with a non-zero status.
+`,
+}
+
+var doctorF = cmdDoctor.Flag.String("f", "./monhang.json", "configuration file")
+var doctorStrictF = cmdDoctor.Flag.Bool("strict", false, "treat warnings as failures")
+
+// checkStatus is the outcome of a single doctor check.
+type checkStatus string
+
+const (
+	checkPass checkStatus = "PASS"
+	checkWarn checkStatus = "WARN"
+	checkFail checkStatus = "FAIL"
+)
+
+// doctor accumulates the results of the checks.
+type doctor struct {
+	failed int
+	warned int
+}
+
+func (d *doctor) report(status checkStatus, format string, args ...interface{}) {
+	switch status {
+	case checkFail:
+		d.failed++
+	case checkWarn:
+		d.warned++
+	}
+	fmt.Printf("[%s] %s\n", status, fmt.Sprintf(format, args...))
+}
+
+func (d *doctor) checkGit() bool {
+	if err := requireGit(); err != nil {
+		d.report(checkFail, "%v", err)
+		return false
+	}
+	version, err := gitOutput(".", nil, "--version")
+	if err != nil {
+		d.report(checkFail, "%v", err)
+		return false
+	}
+	d.report(checkPass, "%s", version)
+	return true
+}
+
+// checkComponent checks the working tree of dep. locked holds the commits
+// recorded in the lock file, if any.
+func (d *doctor) checkComponent(dep *ComponentRef, locked map[string]string) {
+	dir := dep.Dir()
+	if _, err := os.Stat(dir); err != nil {
+		d.report(checkWarn, "%s: not present, run monhang boot", dep.Name)
+		return
+	}
+	if !isGitRepository(dir) {
+		d.report(checkFail, "%s: not a git repository", dep.Name)
+		return
+	}
+
+	// Stashes are easily forgotten, report them even on a clean tree
+	if stashes, err := gitOutput(dir, nil, "stash", "list"); err == nil && stashes != "" {
+		lines := strings.Split(stashes, "\n")
+		latest := strings.SplitN(lines[0], ": ", 2)
+		d.report(checkWarn, "%s: %d stashes, latest: %s", dep.Name, len(lines), latest[len(latest)-1])
+	}
+
+	status, err := gitOutput(dir, nil, "status", "--porcelain")
+	if err != nil {
+		d.report(checkFail, "%s: %v", dep.Name, err)
+		return
+	}
+	if status != "" {
+		d.report(checkWarn, "%s: working tree has local changes", dep.Name)
+		return
+	}
+	if _, err := gitOutput(dir, nil, "symbolic-ref", "-q", "HEAD"); err != nil {
+		// Boot leaves components detached at their version or locked commit
+		commit, err := dep.Commit()
+		if err != nil {
+			d.report(checkFail, "%s: %v", dep.Name, err)
+			return
+		}
+		if commit == locked[dep.Name] {
+			d.report(checkPass, "%s: clean, at the locked commit %s", dep.Name, shortCommit(commit))
+			return
+		}
+		if dep.Version != "" {
+			if expected, err := dep.Resolve(dep.Version); err == nil && expected == commit {
+				d.report(checkPass, "%s: clean, at %s", dep.Name, dep.Version)
+				return
+			}
+		}
+		d.report(checkWarn, "%s: detached HEAD at %s", dep.Name, shortCommit(commit))
+		return
+	}
+	d.report(checkPass, "%s: clean", dep.Name)
+}
+
+func runDoctor(cmd *Command, args []string) {
+	var d doctor
+	gitFound := d.checkGit()
+
+	proj, err := parseProjectFile(*doctorF)
+	if err != nil {
+		d.report(checkFail, "%v", err)
+	} else {
+		proj.processDeps()
+		proj.Sort()
+		waves, err := proj.waves(allDepKinds())
+		if err != nil {
+			d.report(checkFail, "%s: %v", *doctorF, err)
+		} else {
+			d.report(checkPass, "%s is valid", *doctorF)
+		}
+		var locked map[string]string
+		if lock, err := readLock(lockFilename); err == nil {
+			locked = lock.commits()
+		} else if !os.IsNotExist(err) {
+			d.report(checkFail, "%s: %v", lockFilename, err)
+		}
+		if gitFound {
+			for _, wave := range waves {
+				for _, dep := range wave {
+					d.checkComponent(dep, locked)
+				}
+			}
+		}
+	}
+
+	fmt.Printf("%d failures, %d warnings\n", d.failed, d.warned)
+	if d.failed > 0 || (*doctorStrictF && d.warned > 0) {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	cmdDoctor.Run = runDoctor // break init loop
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDoctorDetachedHead(t *testing.T) {
+	defer stubbed()()
+	dir, err := ioutil.TempDir("", "monhang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(dir+"/.git", 0755)
+
+	gitOutput = func(dir string, env []string, args ...string) (string, error) {
+		switch args[len(args)-1] {
+		case "HEAD":
+			if args[0] == "symbolic-ref" {
+				return "", errors.New("git symbolic-ref: ")
+			}
+			return "0123456789abcdef", nil
+		case "v1.0.0^{commit}":
+			return "0123456789abcdef", nil
+		}
+		return "", nil
+	}
+
+	tests := []struct {
+		dep    ComponentRef
+		locked map[string]string
+		warned int
+	}{
+		{ComponentRef{Name: "lib1", Version: "v1.0.0", Path: dir}, nil, 0},
+		{ComponentRef{Name: "lib1", Version: "v2.0.0", Path: dir}, map[string]string{"lib1": "0123456789abcdef"}, 0},
+		{ComponentRef{Name: "lib1", Version: "v2.0.0", Path: dir}, nil, 1},
+	}
+	for _, test := range tests {
+		var d doctor
+		d.checkComponent(&test.dep, test.locked)
+		if d.warned != test.warned || d.failed != 0 {
+			t.Errorf("%s locked at %v: %d warnings and %d failures, expected %d warnings",
+				test.dep.Version, test.locked, d.warned, d.failed, test.warned)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors that can be matched with errors.Is against the errors returned by
+// component operations.
+var (
+	ErrCloneFailed    = errors.New("clone failed")
+	ErrCheckoutFailed = errors.New("checkout failed")
+	ErrAuthRequired   = errors.New("authentication required")
+	ErrRepoNotFound   = errors.New("repository not found")
+	ErrRefNotFound    = errors.New("ref not found")
+	ErrRefMismatch    = errors.New("ref mismatch")
+)
+
+// gitReasons maps fragments of git error messages to the error explaining
+// them.
+var gitReasons = []struct {
+	msg    string
+	reason error
+}{
+	{"Authentication failed", ErrAuthRequired},
+	{"could not read Username", ErrAuthRequired},
+	{"Permission denied", ErrAuthRequired},
+	{"Repository not found", ErrRepoNotFound},
+	{"does not appear to be a git repository", ErrRepoNotFound},
+	{"does not exist", ErrRepoNotFound},
+	{"did not match any file(s) known to git", ErrRefNotFound},
+	{"unknown revision", ErrRefNotFound},
+	{"reference is not a tree", ErrRefNotFound},
+	{"couldn't find remote ref", ErrRefNotFound},
+}
+
+// GitError is returned when a git operation on a component fails.
+type GitError struct {
+	Op     string // clone, fetch, checkout or submodule
+	Reason error  // one of the Err* errors above, or nil if unknown
+	Err    error  // the underlying error
+}
+
+func newGitError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	gerr := &GitError{Op: op, Err: err}
+	for _, r := range gitReasons {
+		if strings.Contains(err.Error(), r.msg) {
+			gerr.Reason = r.reason
+			break
+		}
+	}
+	return gerr
+}
+
+func (e *GitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is the failure of the given operation or has
+// the given reason.
+func (e *GitError) Is(target error) bool {
+	switch target {
+	case ErrCloneFailed:
+		return e.Op == "clone"
+	case ErrCheckoutFailed:
+		return e.Op == "checkout"
+	}
+	return e.Reason != nil && e.Reason == target
+}
+
+// errorHints suggest how to fix the failures with a known reason.
+var errorHints = []struct {
+	err  error
+	hint string
+}{
+	{ErrAuthRequired, "check your git credentials, or set MONHANG_GIT_TOKEN for HTTPS repositories"},
+	{ErrRepoNotFound, "check the repo and repoconfig base of the component"},
+	{ErrRefNotFound, "check that the version exists upstream, monhang outdated lists the latest one"},
+	{ErrRefMismatch, "check out the version in the component, or remove it so that boot clones it again"},
+}
+
+// errorHint returns a suggested remediation for err, or "" if there is none.
+func errorHint(err error) string {
+	for _, h := range errorHints {
+		if errors.Is(err, h.err) {
+			return h.hint
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGitError(t *testing.T) {
+	defer stubbed()()
+	git = func(ctx context.Context, env, args []string) error {
+		return errors.New("git clone: fatal: Authentication failed for 'https://example.com/lib1.git/'")
+	}
+
+	err := ComponentRef{Name: "lib1", Repo: "https://example.com/lib1.git"}.Fetch(context.Background())
+	if !errors.Is(err, ErrCloneFailed) {
+		t.Error("clone error not reported as ErrCloneFailed:", err)
+	}
+	if !errors.Is(err, ErrAuthRequired) {
+		t.Error("authentication error not reported as ErrAuthRequired:", err)
+	}
+	if errors.Is(err, ErrCheckoutFailed) || errors.Is(err, ErrRepoNotFound) {
+		t.Error("clone error matches unrelated errors:", err)
+	}
+
+	var gerr *GitError
+	if !errors.As(err, &gerr) || gerr.Op != "clone" {
+		t.Error("clone error is not a GitError:", err)
+	}
+}
+
+func TestErrorHint(t *testing.T) {
+	err := newGitError("checkout", errors.New("git checkout: error: pathspec 'v9.9.9' did not match any file(s) known to git"))
+	if hint := errorHint(err); !strings.Contains(hint, "version exists upstream") {
+		t.Error("invalid hint for an unknown version:", hint)
+	}
+	if hint := errorHint(errors.New("post_clone hook: exit status 1")); hint != "" {
+		t.Error("unexpected hint for an unknown error:", hint)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/twmb/algoimpl/go/graph"
+	"io"
+	"os"
+)
+
+var cmdGraph = &Command{
+	Name:  "graph",
+	Args:  "[-f configfile] [-format dot|mermaid]",
+	Short: "print the dependency graph",
+	Long: `
+Graph prints the dependency graph of the component described in the given
+configuration file. The default output is in Graphviz DOT format, use
+-format mermaid to get a Mermaid flowchart instead.
+`,
+}
+
+var graphF = cmdGraph.Flag.String("f", "./monhang.json", "configuration file")
+var graphFormatF = cmdGraph.Flag.String("format", "dot", "output format: dot or mermaid")
+
+// depColors are the edge colors used for each dependency type.
+var depColors = map[string]string{
+	"build":   "black",
+	"runtime": "blue",
+	"install": "darkgreen",
+}
+
+// nodeName returns the component name stored in a graph node.
+func nodeName(n graph.Node) string {
+	switch v := (*n.Value).(type) {
+	case *Project:
+		return v.Name
+	case *ComponentRef:
+		return v.Name
+	}
+	return ""
+}
+
+func writeDot(w io.Writer, proj *Project) {
+	fmt.Fprintf(w, "digraph %q {\n", proj.Name)
+	for _, n := range proj.sorted {
+		fmt.Fprintf(w, "\t%q;\n", nodeName(n))
+	}
+	for _, n := range proj.sorted {
+		for _, m := range proj.graph.Neighbors(n) {
+			dep := (*m.Value).(*ComponentRef)
+			fmt.Fprintf(w, "\t%q -> %q [label=%q, color=%q];\n",
+				nodeName(n), dep.Name, dep.kind, depColors[dep.kind])
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func writeMermaid(w io.Writer, proj *Project) {
+	// Mermaid ids can't hold every character allowed in a component name,
+	// so nodes are identified by their position and labeled with the name.
+	ids := make(map[string]string)
+	fmt.Fprintln(w, "graph TD")
+	for i, n := range proj.sorted {
+		ids[nodeName(n)] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(w, "\tn%d[%q]\n", i, nodeName(n))
+	}
+
+	link := 0
+	for _, n := range proj.sorted {
+		for _, m := range proj.graph.Neighbors(n) {
+			dep := (*m.Value).(*ComponentRef)
+			fmt.Fprintf(w, "\t%s -->|%s| %s\n", ids[nodeName(n)], dep.kind, ids[dep.Name])
+			fmt.Fprintf(w, "\tlinkStyle %d stroke:%s\n", link, depColors[dep.kind])
+			link++
+		}
+	}
+}
+
+func runGraph(cmd *Command, args []string) {
+	proj, err := parseProjectFile(*graphF)
+	check(err)
+
+	proj.processDeps()
+	proj.Sort()
+
+	switch *graphFormatF {
+	case "dot":
+		writeDot(os.Stdout, proj)
+	case "mermaid":
+		writeMermaid(os.Stdout, proj)
+	default:
+		mglog.Fatal("Unknown graph format: ", *graphFormatF)
+	}
+}
+
+func init() {
+	cmdGraph.Run = runGraph // break init loop
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteDot(t *testing.T) {
+	proj := &Project{}
+	proj.Name = "top"
+	proj.Deps.Build = []ComponentRef{{Name: "lib1"}, {Name: "lib2"}}
+	proj.processDeps()
+	proj.Sort()
+
+	var buf bytes.Buffer
+	writeDot(&buf, proj)
+	out := buf.String()
+
+	for _, edge := range []string{
+		`"top" -> "lib1" [label="build", color="black"];`,
+		`"top" -> "lib2" [label="build", color="black"];`,
+	} {
+		if !strings.Contains(out, edge) {
+			t.Errorf("missing edge %s in:\n%s", edge, out)
+		}
+	}
+}
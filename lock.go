@@ -0,0 +1,76 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// lockFilename is the file recording the commits of a workspace.
+const lockFilename = "monhang.lock"
+
+// lockedComponent records the commit a component was fetched at.
+type lockedComponent struct {
+	Name   string `json:"name"`
+	Repo   string `json:"repo"`
+	Commit string `json:"commit"`
+}
+
+// lockFile records the exact state of a workspace after boot, so that it can
+// be reproduced with boot -frozen.
+type lockFile struct {
+	Components []lockedComponent `json:"components"`
+}
+
+// update records the commits of the fetched components. Components not
+// fetched this time are kept as they were.
+func (lock *lockFile) update(results []fetchResult) {
+	index := make(map[string]int)
+	for i, c := range lock.Components {
+		index[c.Name] = i
+	}
+	for _, res := range results {
+		if res.Commit == "" {
+			continue
+		}
+		c := lockedComponent{Name: res.Name, Repo: res.Repo, Commit: res.Commit}
+		if i, ok := index[res.Name]; ok {
+			lock.Components[i] = c
+		} else {
+			index[res.Name] = len(lock.Components)
+			lock.Components = append(lock.Components, c)
+		}
+	}
+}
+
+func readLock(filename string) (*lockFile, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var lock lockFile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+func (lock *lockFile) write(filename string) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// commits returns the locked commits by component name.
+func (lock *lockFile) commits() map[string]string {
+	commits := make(map[string]string)
+	for _, c := range lock.Components {
+		commits[c.Name] = c.Commit
+	}
+	return commits
+}
@@ -0,0 +1,178 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/op/go-logging"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var mglog = logging.MustGetLogger("monhang")
+var format = logging.MustStringFormatter(
+	`%{color}%{time:15:04:05.000} %{shortfunc} ▶ %{level:.4s} %{id:03x}%{color:reset} %{message}`,
+)
+var plainFormat = logging.MustStringFormatter(
+	`%{time:15:04:05.000} %{shortfunc} ▶ %{level:.4s} %{id:03x} %{message}`,
+)
+var fileFormat = logging.MustStringFormatter(
+	`%{time:2006-01-02 15:04:05.000} %{shortfunc} ▶ %{level:.4s} %{id:03x} %{message}`,
+)
+
+// jsonFormatter formats each log record as a single line JSON object.
+type jsonFormatter struct{}
+
+func (f jsonFormatter) Format(calldepth int, r *logging.Record, w io.Writer) error {
+	return json.NewEncoder(w).Encode(struct {
+		Time    time.Time `json:"time"`
+		Level   string    `json:"level"`
+		Module  string    `json:"module"`
+		ID      uint64    `json:"id"`
+		Message string    `json:"message"`
+	}{r.Time, r.Level.String(), r.Module, r.ID, r.Message()})
+}
+
+// logConfig holds the logging options.
+type logConfig struct {
+	Format  string
+	Level   string
+	File    string
+	MaxSize int // in megabytes
+	NoColor bool
+}
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnabled reports whether colored output should be used. Colors are
+// disabled by -no-color, by the NO_COLOR environment variable and when stderr
+// is not a terminal.
+func colorEnabled(noColor bool) bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stderr)
+}
+
+// logFormatter returns the formatter for the given log format name.
+func logFormatter(name string, color bool) (logging.Formatter, error) {
+	switch name {
+	case "console":
+		if !color {
+			return plainFormat, nil
+		}
+		return format, nil
+	case "json":
+		return jsonFormatter{}, nil
+	}
+	return nil, fmt.Errorf("unknown log format: %s", name)
+}
+
+// logLevel parses a log level name. Besides the go-logging level names it
+// accepts trace and warn, mapped to the closest available level.
+func logLevel(name string) (logging.Level, error) {
+	switch strings.ToLower(name) {
+	case "trace":
+		return logging.DEBUG, nil
+	case "warn":
+		return logging.WARNING, nil
+	}
+	level, err := logging.LogLevel(name)
+	if err != nil {
+		return level, fmt.Errorf("unknown log level: %s", name)
+	}
+	return level, nil
+}
+
+// rotatingFile is an append only log file that is renamed to <path>.1 once
+// it grows beyond maxSize bytes. A maxSize of zero disables rotation.
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	size    int64
+	file    *os.File
+}
+
+func openLogFile(path string, maxSize int64) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// setupLog configures the log backends. Logs always go to stderr and, if
+// cfg.File is given, are also appended to that file, which is rotated once it
+// exceeds cfg.MaxSize megabytes.
+func setupLog(cfg logConfig) error {
+	formatter, err := logFormatter(cfg.Format, colorEnabled(cfg.NoColor))
+	if err != nil {
+		return err
+	}
+
+	level, err := logLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	backend := logging.NewLogBackend(os.Stderr, "", 0)
+	backends := []logging.Backend{logging.NewBackendFormatter(backend, formatter)}
+
+	if cfg.File != "" {
+		file, err := openLogFile(cfg.File, int64(cfg.MaxSize)<<20)
+		if err != nil {
+			return err
+		}
+		if cfg.Format == "console" {
+			formatter = fileFormat
+		}
+		fileBackend := logging.NewLogBackend(file, "", 0)
+		backends = append(backends, logging.NewBackendFormatter(fileBackend, formatter))
+	}
+
+	logging.SetBackend(backends...)
+	logging.SetLevel(level, "")
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monhang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "monhang.log")
+	f, err := openLogFile(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Write([]byte("0123456789"))
+	f.Write([]byte("abc"))
+
+	old, err := ioutil.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatal("log file was not rotated:", err)
+	}
+	if string(old) != "0123456789" {
+		t.Error("invalid rotated content:", string(old))
+	}
+
+	cur, _ := ioutil.ReadFile(path)
+	if string(cur) != "abc" {
+		t.Error("invalid current content:", string(cur))
+	}
+}
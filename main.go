@@ -21,21 +21,22 @@ package main
 import (
 	"flag"
 	"fmt"
-	"github.com/op/go-logging"
 	"os"
 )
 
-var mglog = logging.MustGetLogger("monhang")
-var format = logging.MustStringFormatter(
-	`%{color}%{time:15:04:05.000} %{shortfunc} ▶ %{level:.4s} %{id:03x}%{color:reset} %{message}`,
-)
+var logFormatF = flag.String("log-format", envDefault("MONHANG_LOG_FORMAT", "console"), "log format: console or json")
+var logLevelF = flag.String("log-level", envDefault("MONHANG_LOG_LEVEL", "info"), "log level: trace, debug, info, warn or error")
+var debugF = flag.Bool("debug", false, "shortcut for -log-level debug")
+var logFileF = flag.String("log-file", "", "also append logs to this file")
+var logMaxSizeF = flag.Int("log-max-size", 0, "rotate the log file after this many megabytes (0 disables)")
+var noColorF = flag.Bool("no-color", false, "disable colored output")
+var noValidateF = flag.Bool("no-validate", false, "do not validate configuration files")
+var gitBinF = flag.String("git-bin", envDefault("MONHANG_GIT_BIN", "git"), "git executable, looked up on PATH unless it is a path")
+var chdirF string
 
-func setupLog() {
-	backend := logging.NewLogBackend(os.Stderr, "", 0)
-	backendFormatter := logging.NewBackendFormatter(backend, format)
-	backendLeveled := logging.AddModuleLevel(backend)
-	backendLeveled.SetLevel(logging.DEBUG, "")
-	logging.SetBackend(backendFormatter)
+func init() {
+	flag.StringVar(&chdirF, "C", "", "run as if monhang was started in this directory")
+	flag.StringVar(&chdirF, "chdir", "", "same as -C")
 }
 
 // Command is an implementation of a godep command
@@ -62,6 +63,24 @@ type Command struct {
 	Flag flag.FlagSet
 }
 
+// envDefault returns the value of the environment variable key, or def if it
+// is unset. It is used to let the environment provide flag defaults.
+func envDefault(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// flagGiven reports whether the flag name was set in fs.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		given = given || f.Name == name
+	})
+	return given
+}
+
 func check(e error) {
 	if e != nil {
 		panic(e)
@@ -74,16 +93,33 @@ func version() {
 
 func usageExit() {
 	version()
-	fmt.Println(`
+	fmt.Print(`
 Usage:
 
-	monhang command [arguments]
+	monhang [options] command [arguments]
 
 The commands are:
 
 	boot        bootstraps a workspace
+	components  list the declared components
+	doctor      diagnose the environment and the workspace
+	graph       print the dependency graph
+	outdated    list dependencies with newer upstream versions
+	verify      check the workspace against monhang.lock
 	version     print monhang version
 
+The options are:
+
+	-log-format   log format, console or json (env MONHANG_LOG_FORMAT)
+	-log-level    log level, trace, debug, info, warn or error (env MONHANG_LOG_LEVEL)
+	-debug        shortcut for -log-level debug
+	-log-file     also append logs to the given file
+	-log-max-size rotate the log file to <file>.1 after this many megabytes
+	-no-color     disable colored output (env NO_COLOR)
+	-C, -chdir    run as if monhang was started in the given directory
+	-no-validate  do not validate configuration files
+	-git-bin      git executable to run (env MONHANG_GIT_BIN)
+
 Use "monhang help [command]" for more information about a command.
 `)
 	os.Exit(0)
@@ -99,16 +135,39 @@ var cmdHelp = &Command{
 
 var commands = []*Command{
 	cmdBoot,
+	cmdComponents,
+	cmdDoctor,
+	cmdGraph,
+	cmdOutdated,
+	cmdVerify,
 	cmdHelp,
 }
 
-func init() {
-	setupLog()
-}
-
 func main() {
 	flag.Usage = usageExit
 	flag.Parse()
+	if chdirF != "" {
+		if err := os.Chdir(chdirF); err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot change to workspace directory:", err)
+			os.Exit(1)
+		}
+	}
+
+	settings, err := loadSettings()
+	check(err)
+	check(settings.apply(flag.CommandLine))
+
+	if *debugF {
+		*logLevelF = "debug"
+	}
+	check(setupLog(logConfig{
+		Format:  *logFormatF,
+		Level:   *logLevelF,
+		File:    *logFileF,
+		MaxSize: *logMaxSizeF,
+		NoColor: *noColorF,
+	}))
+
 	args := flag.Args()
 	if len(args) < 1 {
 		fmt.Println("You must tell monhang what to do!")
@@ -118,6 +177,7 @@ func main() {
 	for _, cmd := range commands {
 		if cmd.Name == args[0] {
 			cmd.Flag.Parse(args[1:])
+			check(settings.apply(&cmd.Flag))
 			cmd.Run(cmd, cmd.Flag.Args())
 		}
 	}
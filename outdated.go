@@ -0,0 +1,145 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var cmdOutdated = &Command{
+	Name:  "outdated",
+	Args:  "[-f configfile]",
+	Short: "list dependencies with newer upstream versions",
+	Long: `
+Outdated looks up the tags of each dependency repository and reports the
+dependencies whose declared version is older than the latest version tag
+upstream.
+`,
+}
+
+var outdatedF = cmdOutdated.Flag.String("f", "./monhang.json", "configuration file")
+
+// compareVersions compares two versions matching versionPattern and returns
+// -1, 0 or 1 if a is older than, equal to or newer than b. Pre-releases are
+// older than the corresponding release.
+func compareVersions(a, b string) int {
+	parse := func(v string) ([3]int, string) {
+		var nums [3]int
+		v = strings.TrimPrefix(v, "v")
+		v, pre := splitPre(v)
+		for i, n := range strings.SplitN(v, ".", 3) {
+			nums[i], _ = strconv.Atoi(n)
+		}
+		return nums, pre
+	}
+
+	na, prea := parse(a)
+	nb, preb := parse(b)
+	for i := range na {
+		if na[i] != nb[i] {
+			if na[i] < nb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case prea == preb:
+		return 0
+	case prea == "":
+		return 1
+	case preb == "":
+		return -1
+	case prea < preb:
+		return -1
+	}
+	return 1
+}
+
+func splitPre(v string) (string, string) {
+	if i := strings.Index(v, "-"); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
+
+// latestTag returns the newest version among the tags of repo. Pre-release
+// versions are skipped unless pre is set.
+func latestTag(repo string, pre bool) (string, error) {
+	// Never block on a credential prompt, private repositories need a token
+	env := append(tokenEnv(repo, gitToken()), "GIT_TERMINAL_PROMPT=0")
+	out, err := gitOutput(".", env, "ls-remote", "--tags", repo)
+	if err != nil {
+		return "", err
+	}
+
+	latest := ""
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		tag := strings.TrimSuffix(strings.TrimPrefix(fields[1], "refs/tags/"), "^{}")
+		if !versionPattern.MatchString(tag) {
+			continue
+		}
+		if _, p := splitPre(tag); p != "" && !pre {
+			continue
+		}
+		if latest == "" || compareVersions(tag, latest) > 0 {
+			latest = tag
+		}
+	}
+	return latest, nil
+}
+
+func runOutdated(cmd *Command, args []string) {
+	if err := requireGit(); err != nil {
+		mglog.Fatal(err)
+	}
+
+	proj, err := parseProjectFile(*outdatedF)
+	check(err)
+	proj.processDeps()
+	proj.Sort()
+	waves, err := proj.waves(allDepKinds())
+	check(err)
+
+	tw := newTable(os.Stdout)
+	defer tw.Flush()
+	fmt.Fprintln(tw, "COMPONENT\tCURRENT\tLATEST\tUPDATE")
+	for _, wave := range waves {
+		for _, dep := range wave {
+			if dep.Version == "" {
+				continue
+			}
+
+			_, pre := splitPre(dep.Version)
+			latest, err := latestTag(resolveRepo(*dep), pre != "")
+			if err != nil {
+				mglog.Error("Cannot list tags of ", dep.Name, ": ", err)
+				fmt.Fprintf(tw, "%s\t%s\t?\terror\n", ellipsize(dep.Name, maxNameWidth()), dep.Version)
+				continue
+			}
+
+			update := "no"
+			if latest != "" && compareVersions(latest, dep.Version) > 0 {
+				update = "yes"
+			}
+			if latest == "" {
+				latest = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", ellipsize(dep.Name, maxNameWidth()), dep.Version, latest, update)
+		}
+	}
+}
+
+func init() {
+	cmdOutdated.Run = runOutdated // break init loop
+}
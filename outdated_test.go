@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.0.0", "1.0.0", 0},
+		{"v1.0.1", "v1.0.0", 1},
+		{"v1.9.0", "v1.10.0", -1},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v2.0.0-rc1", "v2.0.0", -1},
+		{"v2.0.0-rc2", "v2.0.0-rc1", 1},
+	}
+	for _, test := range tests {
+		if got := compareVersions(test.a, test.b); got != test.expected {
+			t.Errorf("compareVersions(%s, %s) = %d, expected %d", test.a, test.b, got, test.expected)
+		}
+	}
+}
+
+func TestLatestTag(t *testing.T) {
+	defer stubbed()()
+	gitOutput = func(dir string, env []string, args ...string) (string, error) {
+		return "a1\trefs/tags/v1.0.0\n" +
+			"a2\trefs/tags/v1.2.0\n" +
+			"a3\trefs/tags/v1.2.0^{}\n" +
+			"a4\trefs/tags/v1.10.0-rc1\n" +
+			"a5\trefs/tags/nightly", nil
+	}
+
+	if latest, _ := latestTag("lib1.git", false); latest != "v1.2.0" {
+		t.Error("invalid latest release:", latest)
+	}
+	if latest, _ := latestTag("lib1.git", true); latest != "v1.10.0-rc1" {
+		t.Error("invalid latest pre-release:", latest)
+	}
+}
+
+func TestLatestTagToken(t *testing.T) {
+	defer stubbed()()
+	t.Setenv("MONHANG_GIT_TOKEN", "s3cret")
+	var got []string
+	gitOutput = func(dir string, env []string, args ...string) (string, error) {
+		got = env
+		return "", nil
+	}
+
+	latestTag("https://example.com/org/lib1.git", false)
+	if !strings.Contains(strings.Join(got, "\n"), "http.https://example.com/.extraHeader") {
+		t.Error("ls-remote is not authenticated:", got)
+	}
+}
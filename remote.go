@@ -0,0 +1,80 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// configCacheDir returns the directory where downloaded configuration files
+// are cached.
+var configCacheDir = func() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "monhang")
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// fetchConfig downloads a configuration file. The file is cached along with
+// its ETag, so that it is only downloaded again when it changed upstream.
+func fetchConfig(url string) ([]byte, error) {
+	key := fmt.Sprintf("%x", sha256.Sum256([]byte(url)))
+	cacheFile := filepath.Join(configCacheDir(), key+".json")
+	etagFile := cacheFile + ".etag"
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if etag, err := ioutil.ReadFile(etagFile); err == nil {
+		if _, err := os.Stat(cacheFile); err == nil {
+			req.Header.Set("If-None-Match", string(etag))
+		}
+	}
+
+	mglog.Info("Downloading configuration file ", url)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		mglog.Debug("Using cached configuration file ", cacheFile)
+		return ioutil.ReadFile(cacheFile)
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("cannot download %s: %s", url, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Caching is best effort, a failure only means downloading again
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		if err := os.MkdirAll(configCacheDir(), 0755); err == nil &&
+			ioutil.WriteFile(cacheFile, data, 0644) == nil {
+			ioutil.WriteFile(etagFile, []byte(etag), 0644)
+		}
+	}
+	return data, nil
+}
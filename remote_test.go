@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestFetchConfig(t *testing.T) {
+	defer stubbed()()
+	dir, err := ioutil.TempDir("", "monhang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configCacheDir = func() string { return dir }
+
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/monhang.json" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"name": "top"}`))
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		data, err := readConfig(srv.URL + "/monhang.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `{"name": "top"}` {
+			t.Error("invalid configuration:", string(data))
+		}
+	}
+	if downloads != 1 {
+		t.Error("cached configuration downloaded again:", downloads)
+	}
+
+	if _, err := readConfig(srv.URL + "/missing.json"); err == nil {
+		t.Error("missing configuration did not fail")
+	}
+}
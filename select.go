@@ -0,0 +1,98 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stringList is a flag value collecting the values of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// unknownComponent returns the error for a component name not in names,
+// suggesting the closest name if there is one close enough.
+func unknownComponent(name string, names []string) error {
+	best, bestDist := "", len(name)/2+1
+	for _, n := range names {
+		if d := editDistance(name, n); d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("unknown component %q, did you mean %q?", name, best)
+	}
+	return fmt.Errorf("unknown component %q", name)
+}
+
+// selectComponents keeps in waves only the named components, given by name
+// or alias. Empty waves are dropped.
+func selectComponents(waves [][]*ComponentRef, names []string) ([][]*ComponentRef, error) {
+	var all []string
+	byName := make(map[string]string)
+	for _, wave := range waves {
+		for _, dep := range wave {
+			all = append(all, dep.Name)
+			byName[dep.Name] = dep.Name
+			if dep.Alias != "" {
+				all = append(all, dep.Alias)
+				byName[dep.Alias] = dep.Name
+			}
+		}
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range names {
+		canonical, ok := byName[name]
+		if !ok {
+			return nil, unknownComponent(name, all)
+		}
+		selected[canonical] = true
+	}
+
+	var result [][]*ComponentRef
+	for _, wave := range waves {
+		var kept []*ComponentRef
+		for _, dep := range wave {
+			if selected[dep.Name] {
+				kept = append(kept, dep)
+			}
+		}
+		if len(kept) > 0 {
+			result = append(result, kept)
+		}
+	}
+	return result, nil
+}
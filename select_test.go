@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSelectComponents(t *testing.T) {
+	waves := [][]*ComponentRef{
+		{{Name: "core"}, {Name: "frontend", Alias: "fe"}},
+		{{Name: "backend"}},
+	}
+
+	selected, err := selectComponents(waves, []string{"backend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 1 || len(selected[0]) != 1 || selected[0][0].Name != "backend" {
+		t.Error("invalid selection:", selected)
+	}
+
+	selected, err = selectComponents(waves, []string{"fe", "frontend"})
+	if err != nil || len(selected) != 1 || len(selected[0]) != 1 || selected[0][0].Name != "frontend" {
+		t.Error("invalid selection by alias:", selected, err)
+	}
+
+	_, err = selectComponents(waves, []string{"frontedn"})
+	if err == nil || err.Error() != `unknown component "frontedn", did you mean "frontend"?` {
+		t.Error("invalid error for a misspelled component:", err)
+	}
+
+	_, err = selectComponents(waves, []string{"xyz"})
+	if err == nil || err.Error() != `unknown component "xyz"` {
+		t.Error("invalid error for an unknown component:", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// summaryFormats are the renderers of the boot summary, by format name.
+var summaryFormats = map[string]func(w io.Writer, results []fetchResult, wall time.Duration){
+	"table":  writeTable,
+	"plain":  writePlain,
+	"csv":    writeCSV,
+	"json":   writeJSON,
+	"github": writeGitHub,
+}
+
+// printSummary writes the fetch results in the given format and returns the
+// number of failures.
+func printSummary(w io.Writer, format string, results []fetchResult, wall time.Duration) int {
+	summaryFormats[format](w, results, wall)
+
+	failed := 0
+	for _, res := range results {
+		if res.Status == "failed" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// writeTiming writes how long fetching took. The wall-clock time is given
+// apart from the summed time of all clones, which differ when cloning in
+// parallel.
+func writeTiming(w io.Writer, results []fetchResult, wall time.Duration) {
+	var total time.Duration
+	var slowest, fastest *fetchResult
+	n := 0
+	for i, res := range results {
+		if res.Status == "present" {
+			continue
+		}
+		d := res.duration()
+		total += d
+		n++
+		if slowest == nil || d > slowest.duration() {
+			slowest = &results[i]
+		}
+		if fastest == nil || d < fastest.duration() {
+			fastest = &results[i]
+		}
+	}
+
+	fmt.Fprintf(w, "total %v (clone time %v)", wall.Round(time.Millisecond), total.Round(time.Millisecond))
+	if n > 0 {
+		fmt.Fprintf(w, ", slowest %s (%v), fastest %s (%v), mean %v",
+			slowest.Name, slowest.duration().Round(time.Millisecond),
+			fastest.Name, fastest.duration().Round(time.Millisecond),
+			(total / time.Duration(n)).Round(time.Millisecond))
+	}
+	fmt.Fprintln(w)
+}
+
+func writeTable(w io.Writer, results []fetchResult, wall time.Duration) {
+	counts := make(map[string]int)
+	tw := newTable(w)
+	fmt.Fprintln(tw, "COMPONENT\tTYPE\tREF\tCOMMIT\tTIME\tSTATUS")
+	for _, res := range results {
+		counts[res.Status]++
+		status := res.Status
+		if res.Fetched {
+			status += " (ref fetched)"
+		}
+		if res.Error != "" {
+			status += ": " + res.Error
+		}
+		ref, commit := res.Ref, shortCommit(res.Commit)
+		if ref == "" {
+			ref = "-"
+		} else if len(ref) == 40 && strings.Trim(ref, "0123456789abcdef") == "" {
+			// -frozen checks out the locked commits
+			ref = shortCommit(ref)
+		}
+		if commit == "" {
+			commit = "-"
+		}
+		elapsed := fmt.Sprintf("%.1fs", res.duration().Seconds())
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", ellipsize(res.Name, maxNameWidth()), res.Kind, ref, commit, elapsed, status)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "cloned %d, present %d, failed %d\n",
+		counts["cloned"], counts["present"], counts["failed"])
+	writeTiming(w, results, wall)
+	writeFailures(w, results)
+}
+
+// writeFailures lists the failed components with their error and, when the
+// cause is known, a hint on how to fix it.
+func writeFailures(w io.Writer, results []fetchResult) {
+	header := false
+	for _, res := range results {
+		if res.Status != "failed" {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(w, "\nFailed components:")
+			header = true
+		}
+		fmt.Fprintf(w, "  %s: %s\n", res.Name, res.Error)
+		if res.Hint != "" {
+			fmt.Fprintf(w, "    hint: %s\n", res.Hint)
+		}
+	}
+}
+
+// writePlain writes one line per component: name, status and duration.
+func writePlain(w io.Writer, results []fetchResult, wall time.Duration) {
+	for _, res := range results {
+		fmt.Fprintf(w, "%s %s %.1fs\n", res.Name, res.Status, res.duration().Seconds())
+	}
+}
+
+func writeCSV(w io.Writer, results []fetchResult, wall time.Duration) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "type", "status", "seconds", "error"})
+	for _, res := range results {
+		seconds := strconv.FormatFloat(res.duration().Seconds(), 'f', 3, 64)
+		cw.Write([]string{res.Name, res.Kind, res.Status, seconds, res.Error})
+	}
+	cw.Flush()
+}
+
+func writeJSON(w io.Writer, results []fetchResult, wall time.Duration) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	check(enc.Encode(results))
+}
+
+// githubEscape escapes s for use in a GitHub Actions workflow command. Values
+// of properties, like the title, also need their separators escaped.
+func githubEscape(s string, property bool) string {
+	r := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	if property {
+		r = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+	}
+	return r.Replace(s)
+}
+
+// writeGitHub writes the results as GitHub Actions workflow commands: a
+// collapsible group per component and an error annotation for each failure.
+func writeGitHub(w io.Writer, results []fetchResult, wall time.Duration) {
+	for _, res := range results {
+		fmt.Fprintf(w, "::group::%s %s\n", githubEscape(res.Name, false), res.Status)
+		fmt.Fprintf(w, "repo: %s\ntype: %s\ntime: %.1fs\n", res.Repo, res.Kind, res.duration().Seconds())
+		if res.Commit != "" {
+			fmt.Fprintf(w, "commit: %s\n", res.Commit)
+		}
+		if res.Output != "" {
+			fmt.Fprintln(w, res.Output)
+		}
+		fmt.Fprintln(w, "::endgroup::")
+	}
+	for _, res := range results {
+		if res.Status != "failed" {
+			continue
+		}
+		msg := strings.SplitN(res.Error, "\n", 2)[0]
+		fmt.Fprintf(w, "::error title=%s::%s\n", githubEscape(res.Name, true), githubEscape(msg, false))
+	}
+}
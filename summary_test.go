@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testResults() []fetchResult {
+	start := time.Date(2016, 6, 1, 10, 0, 0, 0, time.UTC)
+	return []fetchResult{
+		{Name: "lib1", Kind: "build", Status: "cloned", StartTime: start, EndTime: start.Add(1500 * time.Millisecond)},
+		{Name: "lib2", Kind: "runtime", Status: "failed", Error: "git clone: not found",
+			err: errors.New("not found"), StartTime: start, EndTime: start.Add(time.Second)},
+	}
+}
+
+func TestPrintSummaryFormats(t *testing.T) {
+	tests := map[string]string{
+		"plain": "lib1 cloned 1.5s\nlib2 failed 1.0s\n",
+		"csv":   "name,type,status,seconds,error\nlib1,build,cloned,1.500,\nlib2,runtime,failed,1.000,git clone: not found\n",
+		"github": "::group::lib1 cloned\nrepo: \ntype: build\ntime: 1.5s\n::endgroup::\n" +
+			"::group::lib2 failed\nrepo: \ntype: runtime\ntime: 1.0s\n::endgroup::\n" +
+			"::error title=lib2::git clone: not found\n",
+	}
+	for format, expected := range tests {
+		var buf bytes.Buffer
+		if failed := printSummary(&buf, format, testResults(), time.Second); failed != 1 {
+			t.Errorf("%s: expected 1 failure, got %d", format, failed)
+		}
+		if buf.String() != expected {
+			t.Errorf("%s: unexpected output:\n%s", format, buf.String())
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// minNameWidth is the narrowest the component name column is made to fit
+// the terminal.
+const minNameWidth = 12
+
+// newTable returns a writer aligning tab separated columns to their widest
+// cell. It must be flushed after the last row.
+func newTable(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+}
+
+// terminalSize returns the width of the terminal attached to stdout.
+var terminalSize = func() (int, error) {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	return width, err
+}
+
+// terminalWidth returns the width given by COLUMNS, or else the one of the
+// terminal attached to stdout, or 0 if it is unknown.
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	if width, err := terminalSize(); err == nil && width > 0 {
+		return width
+	}
+	return 0
+}
+
+// maxNameWidth returns the widest the component name column can be: half the
+// terminal width, or 0 for no limit if it is unknown.
+func maxNameWidth() int {
+	columns := terminalWidth()
+	if columns == 0 {
+		return 0
+	}
+	if columns/2 < minNameWidth {
+		return minNameWidth
+	}
+	return columns / 2
+}
+
+// ellipsize shortens s to width characters, ending it with an ellipsis. A
+// width of 0 leaves s unchanged.
+func ellipsize(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return s
+	}
+	return string(r[:width-1]) + "…"
+}
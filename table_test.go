@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTableWidths(t *testing.T) {
+	defer stubbed()()
+	terminalSize = func() (int, error) { return 0, errors.New("not a terminal") }
+	start := time.Date(2016, 6, 1, 10, 0, 0, 0, time.UTC)
+	results := []fetchResult{
+		{Name: "a-component-with-a-rather-long-name", Kind: "build", Ref: "v1.0.0", Status: "cloned",
+			StartTime: start, EndTime: start.Add(time.Second)},
+		{Name: "lib2", Kind: "runtime", Ref: "v2.0.0", Status: "present", StartTime: start, EndTime: start},
+	}
+
+	t.Setenv("COLUMNS", "")
+	var buf bytes.Buffer
+	writeTable(&buf, results, time.Second)
+	expected := "" +
+		"COMPONENT                           TYPE    REF    COMMIT TIME STATUS\n" +
+		"a-component-with-a-rather-long-name build   v1.0.0 -      1.0s cloned\n" +
+		"lib2                                runtime v2.0.0 -      0.0s present\n"
+	if got := buf.String(); !strings.HasPrefix(got, expected) {
+		t.Errorf("invalid table:\n%s", got)
+	}
+
+	os.Setenv("COLUMNS", "40")
+	buf.Reset()
+	writeTable(&buf, results, time.Second)
+	expected = "" +
+		"COMPONENT            TYPE    REF    COMMIT TIME STATUS\n" +
+		"a-component-with-a-… build   v1.0.0 -      1.0s cloned\n"
+	if got := buf.String(); !strings.HasPrefix(got, expected) {
+		t.Errorf("invalid table for a narrow terminal:\n%s", got)
+	}
+
+	// COLUMNS takes precedence over the terminal width
+	terminalSize = func() (int, error) { return 200, nil }
+	buf.Reset()
+	writeTable(&buf, results, time.Second)
+	if got := buf.String(); !strings.HasPrefix(got, expected) {
+		t.Errorf("COLUMNS is ignored:\n%s", got)
+	}
+
+	os.Setenv("COLUMNS", "")
+	terminalSize = func() (int, error) { return 40, nil }
+	buf.Reset()
+	writeTable(&buf, results, time.Second)
+	if got := buf.String(); !strings.HasPrefix(got, expected) {
+		t.Errorf("invalid table for the terminal width:\n%s", got)
+	}
+}
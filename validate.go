@@ -0,0 +1,251 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// versionPattern matches the versions accepted in configuration files, like
+// 1.0.3 or v2.0.0-rc1.
+var versionPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?$`)
+
+// schemaVersion is the newest configuration format version supported.
+// Configuration files without schema_version use the newest one.
+const schemaVersion = 1
+
+// repoTypes are the supported repository types.
+var repoTypes = []string{"git"}
+
+// componentFields are the fields allowed in a component reference. The
+// toplevel component also accepts projectFields.
+var componentFields = []string{"name", "alias", "version", "repo", "path", "repoconfig", "clone_args", "hooks"}
+var projectFields = []string{"schema_version", "build", "deps", "includes"}
+
+// nestedFields are the fields allowed in the nested configuration objects.
+var nestedFields = map[string][]string{
+	"repoconfig": {"type", "base"},
+	"build":      {"type"},
+	"hooks":      {"post_clone"},
+	"deps":       depKinds,
+}
+
+// validationError lists every problem found in a configuration file. Each
+// problem is prefixed by the JSON pointer of the offending value, like
+// /deps/build/2/version.
+type validationError []string
+
+func (e validationError) Error() string {
+	return "invalid configuration:\n\t" + strings.Join(e, "\n\t")
+}
+
+func (e *validationError) add(pointer, format string, args ...interface{}) {
+	*e = append(*e, pointer+": "+fmt.Sprintf(format, args...))
+}
+
+// checkFields reports the keys of obj not in allowed, and recurses into the
+// nested configuration objects.
+func (e *validationError) checkFields(pointer string, obj map[string]interface{}, allowed []string) {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		known := false
+		for _, a := range allowed {
+			// encoding/json matches field names case insensitively
+			known = known || strings.EqualFold(key, a)
+		}
+		if !known {
+			e.add(pointer+"/"+key, "unknown field")
+			continue
+		}
+
+		switch value := obj[key].(type) {
+		case map[string]interface{}:
+			if fields, ok := nestedFields[strings.ToLower(key)]; ok {
+				e.checkFields(pointer+"/"+key, value, fields)
+			}
+		case []interface{}:
+			if !isDepKind(strings.ToLower(key)) || !strings.HasSuffix(pointer, "/deps") {
+				continue
+			}
+			for i, dep := range value {
+				if dep, ok := dep.(map[string]interface{}); ok {
+					e.checkFields(fmt.Sprintf("%s/%s/%d", pointer, key, i), dep, componentFields)
+				}
+			}
+		}
+	}
+}
+
+func (e *validationError) checkRepoConfig(pointer string, rc *RepoConfig) {
+	if rc == nil {
+		return
+	}
+	valid := false
+	for _, t := range repoTypes {
+		valid = valid || rc.Type == t
+	}
+	if !valid {
+		e.add(pointer+"/repoconfig/type", "unsupported repository type %q", rc.Type)
+	}
+	if rc.Base == "" {
+		e.add(pointer+"/repoconfig/base", "is empty")
+	}
+}
+
+func (e *validationError) checkVersion(pointer, version string) {
+	if version != "" && !versionPattern.MatchString(version) {
+		e.add(pointer+"/version", "invalid version %q", version)
+	}
+}
+
+func (e *validationError) checkDep(pointer string, dep ComponentRef) {
+	if dep.Name == "" {
+		e.add(pointer+"/name", "is required")
+	}
+	if dep.Repo == "" {
+		e.add(pointer+"/repo", "is required")
+	}
+	e.checkVersion(pointer, dep.Version)
+	e.checkRepoConfig(pointer, dep.Repoconfig)
+	if dep.Path != "" && (path.IsAbs(dep.Path) || strings.HasPrefix(path.Clean(dep.Path), "..")) {
+		e.add(pointer+"/path", "must be inside the workspace, got %q", dep.Path)
+	}
+	// Options may take their value as a separate argument, like "--depth", "1"
+	if len(dep.CloneArgs) > 0 && !strings.HasPrefix(dep.CloneArgs[0], "-") {
+		e.add(pointer+"/clone_args/0", "must be a git clone option, got %q", dep.CloneArgs[0])
+	}
+}
+
+// validateProjectJSON validates the contents of a JSON configuration file.
+// Unknown fields are rejected, so that typos are not silently ignored.
+// Included files may leave out the toplevel component name.
+func validateProjectJSON(data []byte, included bool) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	// A newer format may add fields, don't report them as unknown
+	if v, ok := raw["schema_version"].(float64); ok && v > schemaVersion {
+		return fmt.Errorf("schema version %v is newer than the supported version %d, please upgrade monhang", v, schemaVersion)
+	}
+
+	var errs validationError
+	errs.checkFields("", raw, append(componentFields, projectFields...))
+
+	var proj Project
+	if err := json.Unmarshal(data, &proj); err != nil {
+		if te, ok := err.(*json.UnmarshalTypeError); ok {
+			pointer := "/" + strings.Replace(strings.ToLower(te.Field), ".", "/", -1)
+			errs.add(pointer, "must be %s, got %s", te.Type, te.Value)
+			return errs
+		}
+		return err
+	}
+
+	if proj.SchemaVersion < 0 {
+		errs.add("/schema_version", "must not be negative, got %d", proj.SchemaVersion)
+	}
+	if proj.Name == "" && !included {
+		errs.add("/name", "is required")
+	}
+	errs.checkVersion("", proj.Version)
+	errs.checkRepoConfig("", proj.Repoconfig)
+
+	deps := map[string][]ComponentRef{
+		"build":   proj.Deps.Build,
+		"runtime": proj.Deps.Runtime,
+		"install": proj.Deps.Intall,
+	}
+	for _, kind := range depKinds {
+		for i, dep := range deps[kind] {
+			errs.checkDep(fmt.Sprintf("/deps/%s/%d", kind, i), dep)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkDuplicates checks that the components declared more than once, by
+// name or by directory, are the same component: a component can be both a
+// build and a runtime dependency, but two teams can't declare it differently.
+// rc is the repository configuration inherited by components without one.
+func checkDuplicates(deps Dependency, rc *RepoConfig) error {
+	all := append(append(append([]ComponentRef(nil), deps.Build...), deps.Runtime...), deps.Intall...)
+	byName := make(map[string]ComponentRef)
+	byDir := make(map[string]ComponentRef)
+
+	same := func(a, b ComponentRef) bool {
+		return resolveRepo(a) == resolveRepo(b) && a.Version == b.Version && a.Name == b.Name &&
+			a.Alias == b.Alias && filepath.Clean(a.Dir()) == filepath.Clean(b.Dir())
+	}
+
+	var errs validationError
+	for _, dep := range all {
+		if dep.Repoconfig == nil {
+			dep.Repoconfig = rc
+		}
+		dir := filepath.Clean(dep.Dir())
+		if other, ok := byName[dep.Name]; ok {
+			if !same(dep, other) {
+				errs = append(errs, fmt.Sprintf("%s is declared twice with a different repo, version, path or alias", dep.Name))
+			}
+			continue
+		}
+		if other, ok := byDir[dir]; ok {
+			errs = append(errs, fmt.Sprintf("%s and %s are both cloned into %s", other.Name, dep.Name, dir))
+			continue
+		}
+		byName[dep.Name] = dep
+		byDir[dir] = dep
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkAliases checks that each alias is unique, and that it is not the name
+// of another component.
+func checkAliases(deps Dependency) error {
+	all := append(append(append([]ComponentRef(nil), deps.Build...), deps.Runtime...), deps.Intall...)
+	names := make(map[string]string)
+	for _, dep := range all {
+		names[dep.Name] = dep.Name
+	}
+
+	var errs validationError
+	for _, dep := range all {
+		if dep.Alias == "" || dep.Alias == dep.Name {
+			continue
+		}
+		if other, ok := names[dep.Alias]; ok {
+			// A component declared twice repeats its alias
+			if other != dep.Name {
+				errs = append(errs, fmt.Sprintf("alias %q of %s is already used by %s", dep.Alias, dep.Name, other))
+			}
+			continue
+		}
+		names[dep.Alias] = dep.Name
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
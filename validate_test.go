@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateProjectJSON(t *testing.T) {
+	valid := `{
+		"schema_version": 1,
+		"name": "top-app",
+		"version": "1.0.3",
+		"repoconfig": {"type": "git", "base": "git@github.com:monhang/"},
+		"repo": "monhang.git",
+		"build": {"type": "shell"},
+		"deps": {
+			"build": [{"name": "lib1", "version": "v1.0.0", "repo": "lib1.git", "clone_args": ["--depth", "1"]}],
+			"runtime": [{"name": "rt1", "version": "v2.0.0-rc1", "repo": "rt1.git"}]
+		}
+	}`
+	if err := validateProjectJSON([]byte(valid), false); err != nil {
+		t.Error("valid configuration rejected:", err)
+	}
+
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{`{"name": "top", "dpes": {}}`, "/dpes: unknown field"},
+		{`{"name": "top", "deps": {"build": [{"name": "a", "repo": "a.git"}, {"name": "b", "rpeo": "b.git"}]}}`, "/deps/build/1/rpeo: unknown field"},
+		{`{"version": "1.0.0"}`, "/name: is required"},
+		{`{"name": "top", "schema_version": 2, "lockfile": {}}`, "schema version 2 is newer than the supported version 1, please upgrade monhang"},
+		{`{"name": "top", "version": "latest"}`, `/version: invalid version "latest"`},
+		{`{"name": "top", "repoconfig": {"type": "svn", "base": "x"}}`, `/repoconfig/type: unsupported repository type "svn"`},
+		{`{"name": "top", "deps": {"build": [{"name": "lib1"}]}}`, "/deps/build/0/repo: is required"},
+		{`{"name": "top", "deps": {"install": [{"repo": "x.git"}]}}`, "/deps/install/0/name: is required"},
+		{`{"name": "top", "deps": {"build": [{"name": "a", "repo": "a.git", "clone_args": ["x"]}]}}`, `/deps/build/0/clone_args/0: must be a git clone option, got "x"`},
+		{`{"name": "top", "deps": {"build": [{"name": "a", "repo": "a.git", "clone_args": "--depth=1"}]}}`, "clone_args: must be []string, got string"},
+		{`{"name": "top", "deps": {"build": [{"name": "a", "repo": "a.git", "path": "../a"}]}}`, `/deps/build/0/path: must be inside the workspace, got "../a"`},
+		{`{"name": "top", "deps": {"build": [{"name": "a", "repo": "a.git", "hooks": {"post_clon": "make"}}]}}`, "/deps/build/0/hooks/post_clon: unknown field"},
+	}
+	for _, test := range tests {
+		err := validateProjectJSON([]byte(test.config), false)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error %q, got %v", test.config, test.err, err)
+		}
+	}
+}
+
+func TestCheckAliases(t *testing.T) {
+	deps := Dependency{
+		Build:   []ComponentRef{{Name: "frontend-application", Alias: "fe"}, {Name: "core"}},
+		Runtime: []ComponentRef{{Name: "backend", Alias: "be"}},
+	}
+	if err := checkAliases(deps); err != nil {
+		t.Error("unique aliases rejected:", err)
+	}
+
+	deps.Runtime = append(deps.Runtime, ComponentRef{Name: "frontend-application", Alias: "fe"})
+	if err := checkAliases(deps); err != nil {
+		t.Error("alias of a component declared twice rejected:", err)
+	}
+
+	deps.Intall = []ComponentRef{{Name: "frontend-tools", Alias: "fe"}, {Name: "tools", Alias: "core"}}
+	err := checkAliases(deps)
+	for _, msg := range []string{
+		`alias "fe" of frontend-tools is already used by frontend-application`,
+		`alias "core" of tools is already used by core`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected error %q, got %v", msg, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 Thiago Cangussu de Castro Gomes. All rights reserved.
+// Use of this source code is governed by a GNU General Public License
+// version 3 that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+var cmdVerify = &Command{
+	Name:  "verify",
+	Args:  "[-f configfile]",
+	Short: "check the workspace against " + lockFilename,
+	Long: `
+Verify compares the commit checked out in each declared component with the
+commit recorded in ` + lockFilename + ` and prints the components that drifted,
+with both commits. It exits with a non-zero status if any component drifted,
+is missing or is not locked.
+`,
+}
+
+var verifyF = cmdVerify.Flag.String("f", "./monhang.json", "configuration file")
+
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorReset = "\033[0m"
+)
+
+// shortCommit abbreviates a commit hash to 7 characters.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// verifyComponent compares the commit checked out in dep with the locked one.
+// It returns the status, ok if they match, and a description.
+func verifyComponent(dep *ComponentRef, locked map[string]string) (string, string) {
+	lockedCommit, ok := locked[dep.Name]
+	if !ok {
+		return "unlocked", "not in " + lockFilename
+	}
+	if !exists(dep) {
+		return "missing", "locked " + shortCommit(lockedCommit)
+	}
+	commit, err := dep.Commit()
+	if err != nil {
+		return "error", err.Error()
+	}
+	if commit != lockedCommit {
+		return "drift", fmt.Sprintf("locked %s, actual %s", shortCommit(lockedCommit), shortCommit(commit))
+	}
+	return "ok", shortCommit(commit)
+}
+
+// verifyWorkspace writes one line per component comparing its checked out
+// commit with the locked one and returns the number of components that don't
+// match.
+func verifyWorkspace(w io.Writer, deps []*ComponentRef, locked map[string]string, color bool) int {
+	drifted := 0
+	tw := newTable(w)
+	for _, dep := range deps {
+		status, detail := verifyComponent(dep, locked)
+
+		// Colors wrap whole lines, so that they shift every line the same
+		line := fmt.Sprintf("%s\t%s\t%s", ellipsize(dep.Name, maxNameWidth()), status, detail)
+		if status != "ok" {
+			drifted++
+			if color {
+				line = colorRed + line + colorReset
+			}
+		} else if color {
+			line = colorGreen + line + colorReset
+		}
+		fmt.Fprintln(tw, line)
+	}
+	tw.Flush()
+	return drifted
+}
+
+func runVerify(cmd *Command, args []string) {
+	if err := requireGit(); err != nil {
+		mglog.Fatal(err)
+	}
+
+	proj, err := parseProjectFile(*verifyF)
+	check(err)
+	proj.processDeps()
+	proj.Sort()
+
+	lock, err := readLock(lockFilename)
+	if err != nil {
+		mglog.Fatal("Cannot read ", lockFilename, ": ", err)
+	}
+
+	waves, err := proj.waves(allDepKinds())
+	check(err)
+	var deps []*ComponentRef
+	for _, wave := range waves {
+		deps = append(deps, wave...)
+	}
+
+	color := !*noColorF && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	drifted := verifyWorkspace(os.Stdout, deps, lock.commits(), color)
+	fmt.Printf("%d of %d components drifted from %s\n", drifted, len(deps), lockFilename)
+	if drifted > 0 {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	cmdVerify.Run = runVerify // break init loop
+}
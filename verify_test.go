@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestVerifyWorkspace(t *testing.T) {
+	defer stubbed()()
+	dir, err := ioutil.TempDir("", "monhang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gitOutput = func(dir string, env []string, args ...string) (string, error) {
+		return "0123456789abcdef", nil
+	}
+
+	deps := []*ComponentRef{
+		{Name: "lib1", Path: dir},
+		{Name: "lib2", Path: dir},
+		{Name: "lib3", Path: dir},
+		{Name: "lib4", Path: dir + "/missing"},
+	}
+	locked := map[string]string{
+		"lib1": "0123456789abcdef",
+		"lib2": "fedcba9876543210",
+		"lib4": "0123456789abcdef",
+	}
+
+	var buf bytes.Buffer
+	if drifted := verifyWorkspace(&buf, deps, locked, false); drifted != 3 {
+		t.Error("invalid number of drifted components:", drifted)
+	}
+	expected := "" +
+		"lib1 ok       0123456\n" +
+		"lib2 drift    locked fedcba9, actual 0123456\n" +
+		"lib3 unlocked not in monhang.lock\n" +
+		"lib4 missing  locked 0123456\n"
+	if buf.String() != expected {
+		t.Errorf("invalid output:\n%s", buf.String())
+	}
+}